@@ -0,0 +1,654 @@
+package sgphelper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"sgpHelper/racedata"
+)
+
+// API_PREFIX prefix of the versioned json api
+const API_PREFIX = "/api/v1"
+
+// apiError error object of all json replies
+type apiError struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+type apiSeason struct {
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	EntryList bool      `json:"entry_list"`
+	Races     []apiRace `json:"races"`
+}
+
+type apiRace struct {
+	Name    string `json:"name"`
+	Slug    string `json:"slug"`
+	Results bool   `json:"results"`
+	Laps    bool   `json:"laps"`
+}
+
+type apiName struct {
+	Name string `json:"name"`
+}
+
+type apiNewRace struct {
+	Name   string `json:"name"`
+	Format string `json:"format"`
+	Qualy  string `json:"qualy"`
+	Race   string `json:"race"`
+}
+
+type apiDriver struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases"`
+	SGPUserID   string   `json:"sgp_user_id"`
+	Nationality string   `json:"nationality"`
+}
+
+type apiMerge struct {
+	Into string `json:"into"`
+}
+
+type apiLaps struct {
+	Laps string `json:"laps"`
+}
+
+type apiPenalty struct {
+	Pos     int `json:"pos"`
+	Penalty int `json:"penalty"`
+	// Session race or qualy, the race if empty
+	Session string `json:"session"`
+}
+
+func (s *Server) registerAPI(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+API_PREFIX+"/openapi.json", s.handleAPIOpenAPI)
+	mux.HandleFunc(API_PREFIX+"/", s.handleAPINotFound)
+
+	mux.HandleFunc("GET "+API_PREFIX+"/seasons", s.require(RoleRead, s.readLocked(s.handleAPISeasons)))
+	mux.HandleFunc("POST "+API_PREFIX+"/seasons", s.require(RoleAdmin, s.locked(s.handleAPICreateSeason)))
+	mux.HandleFunc("GET "+API_PREFIX+"/seasons/{season}", s.require(RoleRead, s.readLocked(s.handleAPISeason)))
+	mux.HandleFunc("PATCH "+API_PREFIX+"/seasons/{season}", s.require(RoleAdmin, s.locked(s.handleAPIRenameSeason)))
+	mux.HandleFunc("DELETE "+API_PREFIX+"/seasons/{season}", s.require(RoleAdmin, s.locked(s.handleAPIDeleteSeason)))
+	mux.HandleFunc("GET "+API_PREFIX+"/seasons/{season}/standings", s.require(RoleRead, s.readLocked(s.handleAPIStandings)))
+	mux.HandleFunc("GET "+API_PREFIX+"/seasons/{season}/awards", s.require(RoleRead, s.readLocked(s.handleAPIAwards)))
+
+	mux.HandleFunc("GET "+API_PREFIX+"/seasons/{season}/entrylist", s.require(RoleRead, s.readLocked(s.handleAPIEntryList)))
+	mux.HandleFunc("PUT "+API_PREFIX+"/seasons/{season}/entrylist", s.require(RoleAdmin, s.locked(s.handleAPIReplaceEntryList)))
+	mux.HandleFunc("POST "+API_PREFIX+"/seasons/{season}/entrylist", s.require(RoleAdmin, s.locked(s.handleAPIAddEntry)))
+	mux.HandleFunc("GET "+API_PREFIX+"/seasons/{season}/entrylist/{team}", s.require(RoleRead, s.readLocked(s.handleAPIEntry)))
+	mux.HandleFunc("PUT "+API_PREFIX+"/seasons/{season}/entrylist/{team}", s.require(RoleAdmin, s.locked(s.handleAPIUpdateEntry)))
+	mux.HandleFunc("DELETE "+API_PREFIX+"/seasons/{season}/entrylist/{team}", s.require(RoleAdmin, s.locked(s.handleAPIDeleteEntry)))
+
+	mux.HandleFunc("GET "+API_PREFIX+"/drivers", s.require(RoleRead, s.readLocked(s.handleAPIDrivers)))
+	mux.HandleFunc("GET "+API_PREFIX+"/drivers/{id}", s.require(RoleRead, s.readLocked(s.handleAPIDriver)))
+	mux.HandleFunc("PATCH "+API_PREFIX+"/drivers/{id}", s.require(RoleAdmin, s.locked(s.handleAPIUpdateDriver)))
+	mux.HandleFunc("POST "+API_PREFIX+"/drivers/{id}/merge", s.require(RoleAdmin, s.locked(s.handleAPIMergeDriver)))
+	mux.HandleFunc("GET "+API_PREFIX+"/teams/{id}", s.require(RoleRead, s.readLocked(s.handleAPITeam)))
+
+	mux.HandleFunc("GET "+API_PREFIX+"/seasons/{season}/races", s.require(RoleRead, s.readLocked(s.handleAPIRaces)))
+	mux.HandleFunc("POST "+API_PREFIX+"/seasons/{season}/races", s.require(RoleSteward, s.locked(s.handleAPICreateRace)))
+	mux.HandleFunc("GET "+API_PREFIX+"/seasons/{season}/races/{race}", s.require(RoleRead, s.readLocked(s.handleAPIRace)))
+	mux.HandleFunc("PATCH "+API_PREFIX+"/seasons/{season}/races/{race}", s.require(RoleAdmin, s.locked(s.handleAPIRenameRace)))
+	mux.HandleFunc("DELETE "+API_PREFIX+"/seasons/{season}/races/{race}", s.require(RoleAdmin, s.locked(s.handleAPIDeleteRace)))
+	mux.HandleFunc("GET "+API_PREFIX+"/seasons/{season}/races/{race}/results", s.require(RoleRead, s.readLocked(s.handleAPIResults)))
+	mux.HandleFunc("POST "+API_PREFIX+"/seasons/{season}/races/{race}/penalties", s.require(RoleSteward, s.locked(s.handleAPIAddPenalty)))
+	mux.HandleFunc("GET "+API_PREFIX+"/seasons/{season}/races/{race}/laps", s.require(RoleRead, s.readLocked(s.handleAPILaps)))
+	mux.HandleFunc("PUT "+API_PREFIX+"/seasons/{season}/races/{race}/laps", s.require(RoleSteward, s.locked(s.handleAPIReplaceLaps)))
+}
+
+func (s *Server) handleAPIOpenAPI(w http.ResponseWriter, r *http.Request) {
+	b, err := publicFS.ReadFile("public/openapi.json")
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if base(r) != "" {
+		b = bytes.Replace(b, []byte(`"url": "`+API_PREFIX+`"`), []byte(`"url": "`+base(r)+API_PREFIX+`"`), 1)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func (s *Server) handleAPINotFound(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, http.StatusNotFound, fmt.Errorf("%v %v not found", r.Method, r.URL.Path))
+}
+
+func (s *Server) handleAPISeasons(w http.ResponseWriter, r *http.Request) {
+
+	seasons := []apiSeason{}
+	for name := range s.season.Seasons {
+		seasons = append(seasons, s.toAPISeason(name))
+	}
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].Name < seasons[j].Name
+	})
+	writeJSON(w, http.StatusOK, seasons)
+}
+
+func (s *Server) handleAPICreateSeason(w http.ResponseWriter, r *http.Request) {
+
+	var body apiName
+	if err := s.readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.Name == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("season name is required"))
+		return
+	}
+
+	if err := s.season.AddSeason(body.Name); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.toAPISeason(body.Name))
+}
+
+func (s *Server) handleAPISeason(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	if _, found := s.season.Seasons[seasonName]; !found {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("season %v %w", seasonName, racedata.ErrNotFound))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.toAPISeason(seasonName))
+}
+
+func (s *Server) handleAPIRenameSeason(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	requestLogger(r).Info("api rename season", "season", seasonName)
+
+	var body apiName
+	if err := s.readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.Name == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("season name is required"))
+		return
+	}
+
+	if err := s.season.RenameSeason(seasonName, body.Name); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.toAPISeason(body.Name))
+}
+
+func (s *Server) handleAPIDeleteSeason(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	requestLogger(r).Info("api delete season", "season", seasonName)
+
+	if err := s.season.RemoveSeason(seasonName); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleAPIStandings(w http.ResponseWriter, r *http.Request) {
+
+	standings, err := s.season.GetStandings(r.PathValue("season"))
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, standings)
+}
+
+func (s *Server) handleAPIAwards(w http.ResponseWriter, r *http.Request) {
+	awards, err := s.season.GetSeasonAwards(r.PathValue("season"))
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, awards)
+}
+
+func (s *Server) handleAPIDrivers(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.season.GetDrivers())
+}
+
+func (s *Server) handleAPIUpdateDriver(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	requestLogger(r).Info("api update driver", "driver", id)
+
+	var body apiDriver
+	if err := s.readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	err := s.season.UpdateDriver(id, racedata.RegisteredDriver{
+		Name:        body.Name,
+		Aliases:     body.Aliases,
+		SGPUserID:   body.SGPUserID,
+		Nationality: body.Nationality,
+	})
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	s.handleAPIDriver(w, r)
+}
+
+// handleAPIMergeDriver merge the driver as duplicate into the driver of
+// the body and return the profile of the merged driver
+func (s *Server) handleAPIMergeDriver(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	var body apiMerge
+	if err := s.readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	requestLogger(r).Info("api merge driver", "duplicate", id, "into", body.Into)
+
+	if err := s.season.MergeDrivers(id, body.Into); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	s.handleAPIDriver(w, r)
+}
+
+func (s *Server) handleAPIDriver(w http.ResponseWriter, r *http.Request) {
+	profile, err := s.season.GetDriverProfile(r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, profile)
+}
+
+func (s *Server) handleAPITeam(w http.ResponseWriter, r *http.Request) {
+	profile, err := s.season.GetTeamProfile(r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, profile)
+}
+
+func (s *Server) handleAPIEntryList(w http.ResponseWriter, r *http.Request) {
+	entryList, err := s.season.GetSeasonEntryList(r.PathValue("season"))
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entryList)
+}
+
+func (s *Server) handleAPIReplaceEntryList(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	requestLogger(r).Info("api replace entry list", "season", seasonName)
+
+	entryList := racedata.EntryList{}
+	if err := s.readJSON(w, r, &entryList); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	s.writeEntryList(w, seasonName, &entryList, http.StatusOK)
+}
+
+func (s *Server) handleAPIAddEntry(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	requestLogger(r).Info("api add entry", "season", seasonName)
+
+	var entry racedata.Driver
+	if err := s.readJSON(w, r, &entry); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	entryList, err := s.season.GetSeasonEntryList(seasonName)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	for _, e := range *entryList {
+		if e.Team == entry.Team {
+			writeAPIError(w, http.StatusConflict, fmt.Errorf("team %v %w", entry.Team, racedata.ErrNotUnique))
+			return
+		}
+	}
+	*entryList = append(*entryList, entry)
+
+	s.writeEntryList(w, seasonName, entryList, http.StatusCreated)
+}
+
+func (s *Server) handleAPIEntry(w http.ResponseWriter, r *http.Request) {
+	entryList, idx, err := s.findEntry(r)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, (*entryList)[idx])
+}
+
+func (s *Server) handleAPIUpdateEntry(w http.ResponseWriter, r *http.Request) {
+	requestLogger(r).Info("api update entry", "season", r.PathValue("season"), "team", r.PathValue("team"))
+
+	entryList, idx, err := s.findEntry(r)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+
+	var entry racedata.Driver
+	if err := s.readJSON(w, r, &entry); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	(*entryList)[idx] = entry
+
+	s.writeEntryList(w, r.PathValue("season"), entryList, http.StatusOK)
+}
+
+func (s *Server) handleAPIDeleteEntry(w http.ResponseWriter, r *http.Request) {
+	requestLogger(r).Info("api delete entry", "season", r.PathValue("season"), "team", r.PathValue("team"))
+
+	entryList, idx, err := s.findEntry(r)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	*entryList = append((*entryList)[:idx], (*entryList)[idx+1:]...)
+
+	s.writeEntryList(w, r.PathValue("season"), entryList, http.StatusOK)
+}
+
+func (s *Server) findEntry(r *http.Request) (*racedata.EntryList, int, error) {
+	seasonName := r.PathValue("season")
+	team := r.PathValue("team")
+
+	entryList, err := s.season.GetSeasonEntryList(seasonName)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i, e := range *entryList {
+		if e.Team == team {
+			return entryList, i, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("team %v in entry list of season %v %w", team, seasonName, racedata.ErrNotFound)
+}
+
+func (s *Server) writeEntryList(w http.ResponseWriter, seasonName string, entryList *racedata.EntryList, status int) {
+	if err := s.season.SetEntryList(seasonName, entryList); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, status, entryList)
+}
+
+func (s *Server) handleAPIRaces(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	if _, found := s.season.Seasons[seasonName]; !found {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("season %v %w", seasonName, racedata.ErrNotFound))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.toAPISeason(seasonName).Races)
+}
+
+// handleAPICreateRace create a race, qualy and race results are optional
+// and are validated before the race is created
+func (s *Server) handleAPICreateRace(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	requestLogger(r).Info("api create race", "season", seasonName)
+
+	var body apiNewRace
+	if err := s.readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.Name == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("race name is required"))
+		return
+	}
+
+	withResults := body.Qualy != "" || body.Race != ""
+	if withResults {
+		report := racedata.ValidateResults(body.Format, []byte(body.Qualy), []byte(body.Race))
+		report.Season = seasonName
+		report.Race = body.Name
+		if !report.Ok {
+			writeJSON(w, http.StatusUnprocessableEntity, report)
+			return
+		}
+	}
+
+	if err := s.season.AddRace(seasonName, body.Name); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+
+	if withResults {
+		if err := s.season.ImportResults(seasonName, body.Name, body.Format, []byte(body.Qualy), []byte(body.Race)); err != nil {
+			s.season.RemoveRace(seasonName, body.Name)
+			writeAPIError(w, apiErrorStatus(err), err)
+			return
+		}
+	}
+
+	race, err := s.findRace(seasonName, body.Name)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, race)
+}
+
+func (s *Server) handleAPIRace(w http.ResponseWriter, r *http.Request) {
+	race, err := s.findRace(r.PathValue("season"), r.PathValue("race"))
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, race)
+}
+
+func (s *Server) handleAPIRenameRace(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	raceName := r.PathValue("race")
+	requestLogger(r).Info("api rename race", "season", seasonName, "race", raceName)
+
+	var body apiName
+	if err := s.readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if body.Name == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("race name is required"))
+		return
+	}
+
+	if err := s.season.RenameRace(seasonName, raceName, body.Name); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+
+	race, err := s.findRace(seasonName, body.Name)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, race)
+}
+
+func (s *Server) handleAPIDeleteRace(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	raceName := r.PathValue("race")
+	requestLogger(r).Info("api delete race", "season", seasonName, "race", raceName)
+
+	if err := s.season.RemoveRace(seasonName, raceName); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAPIResults race result with and without penalties, only one
+// of them if the query parameter penalties is true or false
+func (s *Server) handleAPIResults(w http.ResponseWriter, r *http.Request) {
+
+	raceResult, err := s.raceResult(r.PathValue("season"), r.PathValue("race"))
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+
+	if p := r.URL.Query().Get("penalties"); p != "" {
+		withPenalties, err := strconv.ParseBool(p)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid penalties parameter %v", p))
+			return
+		}
+		if withPenalties {
+			raceResult.RaceResult = nil
+		} else {
+			raceResult.RaceResultWithPenalty = nil
+		}
+	}
+	writeJSON(w, http.StatusOK, raceResult)
+}
+
+func (s *Server) handleAPIAddPenalty(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	raceName := r.PathValue("race")
+
+	var body apiPenalty
+	if err := s.readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	requestLogger(r).Info("api add penalty", "season", seasonName, "race", raceName, "session", body.Session, "pos", body.Pos, "penalty", body.Penalty)
+
+	if body.Pos < 1 {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid pos %v", body.Pos))
+		return
+	}
+
+	if err := s.season.AddPenalty(seasonName, raceName, body.Session, strconv.Itoa(body.Penalty), strconv.Itoa(body.Pos)); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+
+	raceResult, err := s.raceResult(seasonName, raceName)
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	raceResult.RaceResult = nil
+	writeJSON(w, http.StatusOK, raceResult)
+}
+
+func (s *Server) handleAPILaps(w http.ResponseWriter, r *http.Request) {
+	analysis, err := s.season.GetRaceAnalysis(r.PathValue("season"), r.PathValue("race"))
+	if err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, analysis)
+}
+
+func (s *Server) handleAPIReplaceLaps(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	raceName := r.PathValue("race")
+
+	var body apiLaps
+	if err := s.readJSON(w, r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	requestLogger(r).Info("api replace lap data", "season", seasonName, "race", raceName)
+
+	if _, err := s.raceResult(seasonName, raceName); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	if err := s.season.AddLapData(seasonName, raceName, []byte(body.Laps)); err != nil {
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+	s.handleAPILaps(w, r)
+}
+
+// raceResult race result of an existing race, not found if the race has no results
+func (s *Server) raceResult(seasonName string, raceName string) (*racedata.RaceResult, error) {
+	race, err := s.findRace(seasonName, raceName)
+	if err != nil {
+		return nil, err
+	}
+	if !race.Results {
+		return nil, fmt.Errorf("results of race %v in season %v %w", raceName, seasonName, racedata.ErrNotFound)
+	}
+	return s.season.GetRaceResult(seasonName, raceName)
+}
+
+func (s *Server) findRace(seasonName string, raceName string) (*apiRace, error) {
+	if _, found := s.season.Seasons[seasonName]; !found {
+		return nil, fmt.Errorf("season %v %w", seasonName, racedata.ErrNotFound)
+	}
+	for _, race := range s.toAPISeason(seasonName).Races {
+		if race.Name == raceName {
+			return &race, nil
+		}
+	}
+	return nil, fmt.Errorf("race %v in season %v %w", raceName, seasonName, racedata.ErrNotFound)
+}
+
+func (s *Server) toAPISeason(name string) apiSeason {
+	season := s.season.Seasons[name]
+	a := apiSeason{Name: name, Slug: season.Slug, EntryList: season.EntyListFile != "", Races: []apiRace{}}
+	for _, race := range season.Races {
+		a.Races = append(a.Races, apiRace{Name: race.Name, Slug: race.Slug, Results: race.RaceResultFile != "", Laps: race.LapDataFile != ""})
+	}
+	return a
+}
+
+// apiErrorStatus http status for errors of race data operations
+func apiErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, racedata.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, racedata.ErrNotUnique):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// readJSON decode the json body, other content types are rejected so
+// browsers can not send api requests with cookies from foreign forms
+func (s *Server) readJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		return fmt.Errorf("content type must be application/json")
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadSize*2)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("invalid json body - %v", err)
+	}
+	return nil
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{Status: status, Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("can not write json reply", "error", err)
+	}
+}
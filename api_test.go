@@ -0,0 +1,60 @@
+package sgphelper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sgpHelper/racedata"
+)
+
+func apiRequest(method string, path string, body string) *http.Request {
+	r := httptest.NewRequest(method, API_PREFIX+path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func TestAPIAddPenalty(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, tc := range []struct {
+		body   string
+		status int
+	}{
+		{`{"pos":1,"penalty":5}`, http.StatusOK},
+		{`{"pos":7,"penalty":5}`, http.StatusNotFound},
+		{`{"pos":1,"penalty":-5}`, http.StatusBadRequest},
+		{`{"pos":0,"penalty":5}`, http.StatusBadRequest},
+		{`{"pos":1,"penalty":2,"session":"qualy"}`, http.StatusOK},
+		{`{"pos":1,"penalty":2,"session":"warmup"}`, http.StatusBadRequest},
+	} {
+		w := ts.serve(apiRequest("POST", "/seasons/season-1/races/race-1/penalties", tc.body))
+		if w.Code != tc.status {
+			t.Errorf("%v: status %v, want %v: %v", tc.body, w.Code, tc.status, w.Body.String())
+		}
+	}
+
+	w := ts.serve(apiRequest("GET", "/seasons/season-1/races/race-1/results?penalties=true", ""))
+	var result racedata.RaceResult
+	if err := json.NewDecoder(w.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	penalties := map[uint]string{}
+	for _, line := range result.RaceResultWithPenalty["GT3"] {
+		penalties[line.Pos] = line.Penalty
+	}
+	if penalties[1] != "5" || penalties[2] != "0" {
+		t.Errorf("unexpected penalties %v", penalties)
+	}
+
+	// the qualifying penalty moves team a behind team b
+	qualifying := result.QualiyResult["GT3"]
+	if len(qualifying) != 2 || qualifying[0].Team != "Team B" || qualifying[1].Penalty != "2" {
+		t.Errorf("unexpected qualifying with penalty %+v", qualifying)
+	}
+	if result.Pole["GT3"].Team != "Team B" {
+		t.Errorf("pole %v, want Team B", result.Pole["GT3"].Team)
+	}
+}
@@ -0,0 +1,298 @@
+package sgphelper
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SESSION_COOKIE name of the admin login session cookie
+const SESSION_COOKIE = "sgp_session"
+
+// Role permissions of a request, every role includes the lower ones
+type Role int
+
+const (
+	// RoleNone anonymous access without read permission
+	RoleNone Role = iota
+	// RoleRead results, entry lists and standings
+	RoleRead
+	// RoleSteward additionally penalties and result uploads
+	RoleSteward
+	// RoleAdmin everything, e.g. seasons, races and entry lists
+	RoleAdmin
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleRead:
+		return "read"
+	case RoleSteward:
+		return "steward"
+	case RoleAdmin:
+		return "admin"
+	}
+	return "none"
+}
+
+// ParseRole role of an api token scope
+func ParseRole(scope string) (Role, error) {
+	switch strings.ToLower(scope) {
+	case "read":
+		return RoleRead, nil
+	case "steward":
+		return RoleSteward, nil
+	case "admin":
+		return RoleAdmin, nil
+	}
+	return RoleNone, fmt.Errorf("unknown token scope %v, use read, steward or admin", scope)
+}
+
+type session struct {
+	user    string
+	expires time.Time
+}
+
+// Auth admin logins for the web ui and scoped bearer tokens for automation,
+// requests without credentials get read access if anonymous read is enabled
+type Auth struct {
+	AnonymousRead bool
+	SessionTTL    time.Duration
+
+	admins map[string]string
+	tokens map[string]Role
+
+	mu       sync.Mutex
+	sessions map[string]session
+}
+
+// NewAuth new auth without admins and tokens
+func NewAuth(anonymousRead bool, sessionTTL time.Duration) *Auth {
+	return &Auth{
+		AnonymousRead: anonymousRead,
+		SessionTTL:    sessionTTL,
+		admins:        map[string]string{},
+		tokens:        map[string]Role{},
+		sessions:      map[string]session{},
+	}
+}
+
+// AddAdmin add an admin login for the web ui, the password is either
+// plain text or a bcrypt hash starting with $2
+func (a *Auth) AddAdmin(user string, password string) {
+	a.admins[user] = password
+}
+
+// AddToken add an api token with the role of its scope
+func (a *Auth) AddToken(token string, role Role) {
+	a.tokens[token] = role
+}
+
+// Login check the credentials and start a new session, expired sessions
+// are removed so logins do not pile up
+func (a *Auth) Login(user string, password string) (string, error) {
+	expected, found := a.admins[user]
+	if !found || !checkPassword(password, expected) {
+		return "", fmt.Errorf("invalid user or password")
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for sessionID, s := range a.sessions {
+		if now.After(s.expires) {
+			delete(a.sessions, sessionID)
+		}
+	}
+	a.sessions[id] = session{user: user, expires: now.Add(a.SessionTTL)}
+	return id, nil
+}
+
+// checkPassword compare with a bcrypt hash or in constant time with a
+// plain text password
+func checkPassword(password string, expected string) bool {
+	if strings.HasPrefix(expected, "$2") {
+		return bcrypt.CompareHashAndPassword([]byte(expected), []byte(password)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(password), []byte(expected)) == 1
+}
+
+// Logout end the session
+func (a *Auth) Logout(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, id)
+}
+
+// Role role of the request from bearer token, session cookie or anonymous access
+func (a *Auth) Role(r *http.Request) Role {
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return a.tokenRole(strings.TrimSpace(token))
+	}
+
+	if cookie, err := r.Cookie(SESSION_COOKIE); err == nil && a.validSession(cookie.Value) {
+		return RoleAdmin
+	}
+
+	if a.AnonymousRead {
+		return RoleRead
+	}
+	return RoleNone
+}
+
+// tokenRole role of the token, every token is compared in constant
+// time instead of a map lookup
+func (a *Auth) tokenRole(token string) Role {
+	found := RoleNone
+	for t, role := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			found = role
+		}
+	}
+	return found
+}
+
+func (a *Auth) validSession(id string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s, found := a.sessions[id]
+	if !found {
+		return false
+	}
+	if time.Now().After(s.expires) {
+		delete(a.sessions, id)
+		return false
+	}
+	return true
+}
+
+type roleKey struct{}
+
+// access permissions of the request shown in templates
+type access struct {
+	Auth    bool
+	Steward bool
+	Admin   bool
+	// CSRF token for the forms of the page
+	CSRF string
+}
+
+func (s *Server) access(r *http.Request) access {
+	return access{
+		Auth:    s.auth != nil,
+		Steward: role(r) >= RoleSteward,
+		Admin:   role(r) >= RoleAdmin,
+		CSRF:    csrfToken(r),
+	}
+}
+
+// SetAuth enable authentication, without auth every request is admin
+func (s *Server) SetAuth(auth *Auth) {
+	s.auth = auth
+}
+
+// role role of the request set by require
+func role(r *http.Request) Role {
+	if role, ok := r.Context().Value(roleKey{}).(Role); ok {
+		return role
+	}
+	return RoleNone
+}
+
+// require reject requests without the role, api requests get a json error,
+// anonymous web requests without read access are sent to the login page
+func (s *Server) require(required Role, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		current := RoleAdmin
+		if s.auth != nil {
+			current = s.auth.Role(r)
+		}
+
+		if current < required {
+			requestLogger(r).Warn("forbidden", "method", r.Method, "path", r.URL.Path, "role", current, "required", required)
+			isAPI := strings.HasPrefix(r.URL.Path, "/api/")
+			switch {
+			case isAPI && current == RoleNone:
+				writeAPIError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+			case isAPI:
+				writeAPIError(w, http.StatusForbidden, fmt.Errorf("%v role required", required))
+			case current == RoleNone && r.Method == "GET":
+				http.Redirect(w, r, base(r)+"/login", http.StatusSeeOther)
+			default:
+				http.Error(w, "forbidden", http.StatusForbidden)
+			}
+			return
+		}
+
+		h(w, r.WithContext(context.WithValue(r.Context(), roleKey{}, current)))
+	}
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil {
+		http.Redirect(w, r, base(r)+"/", http.StatusSeeOther)
+		return
+	}
+
+	s.season.RLock()
+	data := struct {
+		Base  string
+		Brand brand
+		Error string
+		CSRF  string
+	}{Base: base(r), Brand: s.brand(r, ""), CSRF: csrfToken(r)}
+	s.season.RUnlock()
+
+	if r.Method == "POST" {
+		user := r.PostFormValue("user")
+		id, err := s.auth.Login(user, r.PostFormValue("password"))
+		if err == nil {
+			requestLogger(r).Info("admin logged in", "user", user)
+			http.SetCookie(w, &http.Cookie{
+				Name:     SESSION_COOKIE,
+				Value:    id,
+				Path:     cookiePath(r),
+				HttpOnly: true,
+				Secure:   isHTTPS(r),
+				SameSite: http.SameSiteLaxMode,
+				MaxAge:   int(s.auth.SessionTTL.Seconds()),
+			})
+			http.Redirect(w, r, base(r)+"/", http.StatusSeeOther)
+			return
+		}
+		requestLogger(r).Warn("login failed", "user", user)
+		w.WriteHeader(http.StatusUnauthorized)
+		data.Error = err.Error()
+	}
+
+	s.render(w, r, "login.html", data)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth != nil {
+		if cookie, err := r.Cookie(SESSION_COOKIE); err == nil {
+			s.auth.Logout(cookie.Value)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: SESSION_COOKIE, Value: "", Path: cookiePath(r), MaxAge: -1})
+	http.Redirect(w, r, base(r)+"/", http.StatusSeeOther)
+}
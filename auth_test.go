@@ -0,0 +1,154 @@
+package sgphelper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newAuthServer test server with admins and one token per role
+func newAuthServer(t *testing.T, anonymousRead bool) *testServer {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAuth(anonymousRead, time.Hour)
+	auth.AddAdmin("admin", string(hash))
+	auth.AddAdmin("plain", "plain-secret")
+	auth.AddToken("read-token", RoleRead)
+	auth.AddToken("steward-token", RoleSteward)
+	auth.AddToken("admin-token", RoleAdmin)
+
+	ts := newTestServer(t)
+	ts.SetAuth(auth)
+	return ts
+}
+
+// login session cookie of the admin
+func login(t *testing.T, ts *testServer, user string, password string) *http.Cookie {
+	t.Helper()
+	w := ts.serve(formRequest("/login", url.Values{"user": {user}, "password": {password}}))
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("login %v: status %v, want %v", user, w.Code, http.StatusSeeOther)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == SESSION_COOKIE {
+			return c
+		}
+	}
+	t.Fatalf("login %v: no session cookie", user)
+	return nil
+}
+
+func TestRoleMatrix(t *testing.T) {
+	// allowed requests are marked with 0, the status may differ per route
+	const allowed = 0
+
+	requests := []struct {
+		name    string
+		request func(i int) *http.Request
+	}{
+		{"index", func(int) *http.Request { return httptest.NewRequest("GET", "/", nil) }},
+		{"api read", func(int) *http.Request { return apiRequest("GET", "/seasons", "") }},
+		{"penalty form", func(int) *http.Request {
+			return formRequest("/addPenalty/season-1/race-1", url.Values{"pos": {"1"}, "penalty": {"1"}})
+		}},
+		{"api penalty", func(int) *http.Request {
+			return apiRequest("POST", "/seasons/season-1/races/race-1/penalties", `{"pos":1,"penalty":1}`)
+		}},
+		{"new season form", func(i int) *http.Request {
+			return formRequest("/newSeason", url.Values{"new_season_name": {fmt.Sprintf("Season %v", i+2)}})
+		}},
+	}
+
+	for _, tc := range []struct {
+		name          string
+		anonymousRead bool
+		token         string
+		session       bool
+		want          []int
+	}{
+		{"anonymous", false, "", false, []int{http.StatusSeeOther, http.StatusUnauthorized, http.StatusForbidden, http.StatusUnauthorized, http.StatusForbidden}},
+		{"anonymous read", true, "", false, []int{allowed, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+		{"wrong token", true, "guessed", false, []int{http.StatusSeeOther, http.StatusUnauthorized, http.StatusForbidden, http.StatusUnauthorized, http.StatusForbidden}},
+		{"read token", false, "read-token", false, []int{allowed, allowed, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}},
+		{"steward token", false, "steward-token", false, []int{allowed, allowed, allowed, allowed, http.StatusForbidden}},
+		{"admin token", false, "admin-token", false, []int{allowed, allowed, allowed, allowed, allowed}},
+		{"admin session", false, "", true, []int{allowed, allowed, allowed, allowed, allowed}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newAuthServer(t, tc.anonymousRead)
+			var cookie *http.Cookie
+			if tc.session {
+				cookie = login(t, ts, "admin", "secret")
+			}
+
+			for i, req := range requests {
+				r := req.request(i)
+				if tc.token != "" {
+					r.Header.Set("Authorization", "Bearer "+tc.token)
+				}
+				if cookie != nil {
+					r.AddCookie(cookie)
+				}
+				w := ts.serve(r)
+
+				switch want := tc.want[i]; {
+				case want == allowed && w.Code >= 400:
+					t.Errorf("%v: status %v, want access: %v", req.name, w.Code, w.Body.String())
+				case want != allowed && w.Code != want:
+					t.Errorf("%v: status %v, want %v", req.name, w.Code, want)
+				case want == http.StatusSeeOther && w.Header().Get("Location") != "/login":
+					t.Errorf("%v: redirect to %v, want /login", req.name, w.Header().Get("Location"))
+				}
+			}
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	ts := newAuthServer(t, false)
+
+	for _, tc := range []struct {
+		user     string
+		password string
+		status   int
+	}{
+		{"admin", "secret", http.StatusSeeOther},
+		{"admin", "wrong", http.StatusUnauthorized},
+		// the hash itself is not a valid password
+		{"admin", ts.auth.admins["admin"], http.StatusUnauthorized},
+		{"plain", "plain-secret", http.StatusSeeOther},
+		{"plain", "plain-secre", http.StatusUnauthorized},
+		{"nobody", "secret", http.StatusUnauthorized},
+	} {
+		w := ts.serve(formRequest("/login", url.Values{"user": {tc.user}, "password": {tc.password}}))
+		if w.Code != tc.status {
+			t.Errorf("%v/%v: status %v, want %v", tc.user, tc.password, w.Code, tc.status)
+		}
+	}
+}
+
+func TestLoginPurgesExpiredSessions(t *testing.T) {
+	auth := NewAuth(false, time.Hour)
+	auth.AddAdmin("admin", "secret")
+
+	expired, err := auth.Login("admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.sessions[expired] = session{user: "admin", expires: time.Now().Add(-time.Minute)}
+
+	if _, err := auth.Login("admin", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := auth.sessions[expired]; found || len(auth.sessions) != 1 {
+		t.Errorf("expired session was kept, %v sessions", len(auth.sessions))
+	}
+}
@@ -0,0 +1,197 @@
+package sgphelper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"sgpHelper/racedata"
+)
+
+// brand branding of a rendered page, Logo is the url of the logo
+type brand struct {
+	racedata.Branding
+	Logo string
+}
+
+// brand branding of the season for templates, the instance
+// branding if seasonName is empty, race data must be locked.
+// The league list without race data gets the default name
+func (s *Server) brand(r *http.Request, seasonName string) brand {
+	if s.season == nil {
+		return brand{Branding: racedata.Branding{Name: racedata.DEFAULT_BRAND_NAME}}
+	}
+	b := brand{Branding: s.season.GetBranding(seasonName)}
+	if b.LogoFile == "" {
+		return b
+	}
+	b.Logo = base(r) + "/logo"
+	if season, found := s.season.Seasons[seasonName]; found {
+		b.Logo += "/" + season.Slug
+	}
+	return b
+}
+
+// handleLogo logo of the season or the instance
+func (s *Server) handleLogo(w http.ResponseWriter, r *http.Request) {
+	if seasonName := r.PathValue("season"); seasonName != "" {
+		if _, found := s.season.Seasons[seasonName]; !found {
+			http.Error(w, fmt.Sprintf("season %v %v", seasonName, racedata.ErrNotFound), http.StatusNotFound)
+			return
+		}
+	}
+
+	logoFile, err := s.season.GetLogoFile(r.PathValue("season"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if logoFile == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := os.Open(logoFile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// handleBranding show and save the branding of the season
+// or the instance if no season is given
+func (s *Server) handleBranding(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	if seasonName != "" {
+		if _, found := s.season.Seasons[seasonName]; !found {
+			http.Error(w, fmt.Sprintf("season %v %v", seasonName, racedata.ErrNotFound), http.StatusNotFound)
+			return
+		}
+	}
+
+	data := struct {
+		Base        string
+		Season      string
+		SeasonSlug  string
+		Brand       brand
+		Branding    racedata.Branding
+		FooterLinks string
+		Error       string
+		Saved       bool
+		Access      access
+	}{
+		Base:       base(r),
+		Season:     seasonName,
+		SeasonSlug: s.season.Seasons[seasonName].Slug,
+		Access:     s.access(r),
+	}
+
+	if r.Method == "POST" {
+		requestLogger(r).Info("set branding", "season", seasonName)
+		if err := s.saveBranding(r, seasonName); err != nil {
+			requestLogger(r).Warn("invalid branding", "season", seasonName, "error", err)
+			w.WriteHeader(http.StatusBadRequest)
+			data.Error = err.Error()
+		} else {
+			data.Saved = true
+		}
+	}
+
+	branding, err := s.season.GetOwnBranding(seasonName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data.Branding = branding
+	data.FooterLinks = formatFooterLinks(branding.FooterLinks)
+	data.Brand = s.brand(r, seasonName)
+
+	s.render(w, r, "branding.html", data)
+}
+
+// saveBranding validate the form including the logo before anything is
+// stored, so a rejected logo does not reset the other fields
+func (s *Server) saveBranding(r *http.Request, seasonName string) error {
+	links, err := parseFooterLinks(r.PostFormValue("footer_links"))
+	if err != nil {
+		return err
+	}
+	logo, err := readLogo(r)
+	if err != nil {
+		return err
+	}
+	if len(logo) > 0 {
+		if err := racedata.CheckLogo(logo); err != nil {
+			return err
+		}
+	}
+
+	branding := racedata.Branding{
+		Name:           r.PostFormValue("name"),
+		PrimaryColor:   strings.TrimSpace(r.PostFormValue("primary_color")),
+		SecondaryColor: strings.TrimSpace(r.PostFormValue("secondary_color")),
+		FooterLinks:    links,
+	}
+	if err := s.season.SetBranding(seasonName, branding); err != nil {
+		return err
+	}
+
+	if r.PostFormValue("remove_logo") != "" {
+		return s.season.RemoveLogo(seasonName)
+	}
+	if len(logo) == 0 {
+		return nil
+	}
+	return s.season.SetLogo(seasonName, logo)
+}
+
+// readLogo uploaded logo, empty without upload
+func readLogo(r *http.Request) ([]byte, error) {
+	logoFile, _, err := r.FormFile("logo")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer logoFile.Close()
+	return io.ReadAll(logoFile)
+}
+
+// parseFooterLinks one link per line, the title followed by the url
+func parseFooterLinks(text string) ([]racedata.Link, error) {
+	links := []racedata.Link{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		i := strings.LastIndexAny(line, " \t")
+		if i == -1 {
+			return nil, fmt.Errorf("footer link %q needs a title and an url", line)
+		}
+		links = append(links, racedata.Link{
+			Title: strings.TrimSpace(line[:i]),
+			URL:   line[i+1:],
+		})
+	}
+	return links, nil
+}
+
+func formatFooterLinks(links []racedata.Link) string {
+	var b strings.Builder
+	for _, link := range links {
+		fmt.Fprintf(&b, "%v %v\n", link.Title, link.URL)
+	}
+	return b.String()
+}
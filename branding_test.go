@@ -0,0 +1,73 @@
+package sgphelper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testPNG = "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"
+
+// brandingRequest multipart branding form with the csrf token
+func brandingRequest(t *testing.T, path string, fields map[string]string, logo string) *http.Request {
+	t.Helper()
+	fields[CSRF_FIELD] = testCSRFToken
+	files := map[string]string{}
+	if logo != "" {
+		files["logo"] = logo
+	}
+	body, contentType := multipartForm(t, fields, files)
+	r := httptest.NewRequest("POST", path, body)
+	r.Header.Set("Content-Type", contentType)
+	r.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: testCSRFToken})
+	return r
+}
+
+func TestBrandingForm(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, tc := range []struct {
+		name   string
+		path   string
+		fields map[string]string
+		logo   string
+		status int
+	}{
+		{"instance", "/branding", map[string]string{"name": "GT League", "primary_color": "#123456",
+			"footer_links": "Discord https://discord.example\nRules /rules"}, testPNG, http.StatusOK},
+		{"season", "/branding/season-1", map[string]string{"secondary_color": "#abc"}, "", http.StatusOK},
+		{"unknown season", "/branding/season-9", map[string]string{}, "", http.StatusNotFound},
+		{"invalid colour", "/branding", map[string]string{"primary_color": "red"}, "", http.StatusBadRequest},
+		{"link without title", "/branding", map[string]string{"footer_links": "https://discord.example"}, "", http.StatusBadRequest},
+		{"script link", "/branding", map[string]string{"footer_links": "x javascript:alert(1)"}, "", http.StatusBadRequest},
+		{"svg logo", "/branding/season-1", map[string]string{}, `<svg xmlns="http://www.w3.org/2000/svg"></svg>`, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := ts.serve(brandingRequest(t, tc.path, tc.fields, tc.logo))
+			if w.Code != tc.status {
+				t.Errorf("status %v, want %v: %v", w.Code, tc.status, w.Body.String())
+			}
+		})
+	}
+
+	// the rejected posts did not change the saved branding
+	if b := ts.data.GetBranding("Season 1"); b.Name != "GT League" || b.PrimaryColor != "#123456" || b.SecondaryColor != "#abc" {
+		t.Errorf("unexpected branding %+v", b)
+	}
+
+	w := ts.serve(httptest.NewRequest("GET", "/", nil))
+	if body := w.Body.String(); !strings.Contains(body, "GT League") || !strings.Contains(body, "https://discord.example") {
+		t.Errorf("branding not shown on the index page")
+	}
+
+	for _, path := range []string{"/logo", "/logo/season-1"} {
+		w := ts.serve(httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+			t.Errorf("%v: status %v content type %v", path, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+	if w := ts.serve(httptest.NewRequest("GET", "/logo/season-9", nil)); w.Code != http.StatusNotFound {
+		t.Errorf("logo of unknown season: status %v", w.Code)
+	}
+}
@@ -0,0 +1,148 @@
+package sgphelper
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+
+	"sgpHelper/racedata"
+)
+
+// chart size and margins in pixels
+const (
+	chartWidth       = 800
+	chartMarginLeft  = 60
+	chartMarginRight = 160
+	chartMarginY     = 20
+	chartRowHeight   = 20
+	lapChartHeight   = 300
+)
+
+// chartColors line colours of the cars, repeated for large fields
+var chartColors = []string{
+	"#e6194b", "#3cb44b", "#4363d8", "#f58231", "#911eb4", "#42d4f4",
+	"#f032e6", "#9a6324", "#469990", "#800000", "#808000", "#000075",
+}
+
+// chartCarTitle legend text of the car
+func chartCarTitle(car racedata.CarLaps) string {
+	return fmt.Sprintf("#%v %v", car.Startnumber, car.Driver)
+}
+
+// lapX x coordinate of the lap
+func lapX(lap int, laps int) int {
+	if laps == 0 {
+		return chartMarginLeft
+	}
+	return chartMarginLeft + lap*(chartWidth-chartMarginLeft-chartMarginRight)/laps
+}
+
+// chartStart open the svg element of a chart
+func chartStart(b *strings.Builder, label string, height int) {
+	fmt.Fprintf(b, `<svg class="chart" xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-label="%v">`,
+		chartWidth, height, chartWidth, height, html.EscapeString(label))
+}
+
+// lapAxis x axis at y bottom labelled with the laps from first on
+func lapAxis(b *strings.Builder, bottom int, first int, laps int) {
+	fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="gray"/>`, lapX(0, laps), bottom, lapX(laps, laps), bottom)
+	step := 1 + laps/20
+	for lap := first; lap <= laps; lap += step {
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-size="10" text-anchor="middle">%d</text>`, lapX(lap, laps), bottom+14, lap)
+	}
+}
+
+// positionChart svg chart of the position in the split after every lap,
+// the drivers are named at their last position
+func positionChart(split racedata.SplitLaps) template.HTML {
+	b := &strings.Builder{}
+	height := 2*chartMarginY + (len(split.Cars)+1)*chartRowHeight
+	chartStart(b, "positions by lap", height)
+	lapAxis(b, height-chartMarginY, 0, split.Laps)
+	posY := func(pos int) int { return chartMarginY + pos*chartRowHeight }
+
+	for pos := 1; pos <= len(split.Cars); pos++ {
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-size="10" text-anchor="end">P%d</text>`, chartMarginLeft-4, posY(pos)+4, pos)
+	}
+	for i, car := range split.Cars {
+		color := chartColors[i%len(chartColors)]
+		points := []string{}
+		last := 0
+		for lap, pos := range car.Positions {
+			if pos == 0 {
+				break
+			}
+			points = append(points, fmt.Sprintf("%d,%d", lapX(lap, split.Laps), posY(pos)))
+			last = lap
+		}
+		if len(points) == 0 {
+			continue
+		}
+		title := html.EscapeString(chartCarTitle(car))
+		fmt.Fprintf(b, `<polyline fill="none" stroke="%v" stroke-width="2" points="%v"><title>%v</title></polyline>`, color, strings.Join(points, " "), title)
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-size="10" fill="%v">%v</text>`, lapX(last, split.Laps)+6, posY(car.Positions[last])+4, color, title)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// lapTimeChart svg chart of the lap times of every car, the y axis ends at
+// 107% of the fastest lap so slow laps like pit stops are cut off
+func lapTimeChart(split racedata.SplitLaps) template.HTML {
+	b := &strings.Builder{}
+	height := lapChartHeight + (len(split.Cars)+1)*chartRowHeight
+	chartStart(b, "lap times", height)
+
+	fastest := 0
+	for _, car := range split.Cars {
+		for _, t := range car.LapTimes {
+			if t > 0 && (fastest == 0 || t < fastest) {
+				fastest = t
+			}
+		}
+	}
+	slowest := fastest * 107 / 100
+	top, bottom := chartMarginY, lapChartHeight-chartMarginY
+	timeY := func(t int) int {
+		if t > slowest {
+			t = slowest
+		}
+		if slowest == fastest {
+			return bottom
+		}
+		return bottom - (t-fastest)*(bottom-top)/(slowest-fastest)
+	}
+
+	lapAxis(b, bottom, 1, split.Laps)
+	if fastest > 0 {
+		for _, t := range []int{fastest, (fastest + slowest) / 2, slowest} {
+			fmt.Fprintf(b, `<text x="%d" y="%d" font-size="10" text-anchor="end">%v</text>`, chartMarginLeft-4, timeY(t)+4, formatLapTime(t))
+			fmt.Fprintf(b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="lightgray"/>`, chartMarginLeft, timeY(t), lapX(split.Laps, split.Laps), timeY(t))
+		}
+	}
+
+	for i, car := range split.Cars {
+		color := chartColors[i%len(chartColors)]
+		points := []string{}
+		for lap, t := range car.LapTimes {
+			if t > 0 {
+				points = append(points, fmt.Sprintf("%d,%d", lapX(lap, split.Laps), timeY(t)))
+			}
+		}
+		title := html.EscapeString(chartCarTitle(car))
+		if len(points) > 0 {
+			fmt.Fprintf(b, `<polyline fill="none" stroke="%v" stroke-width="1.5" points="%v"><title>%v</title></polyline>`, color, strings.Join(points, " "), title)
+		}
+		y := lapChartHeight + (i+1)*chartRowHeight
+		fmt.Fprintf(b, `<rect x="%d" y="%d" width="12" height="4" fill="%v"/>`, chartMarginLeft, y-6, color)
+		fmt.Fprintf(b, `<text x="%d" y="%d" font-size="10">%v</text>`, chartMarginLeft+18, y, title)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// formatLapTime lap time in milliseconds as m:ss.mmm
+func formatLapTime(ms int) string {
+	return fmt.Sprintf("%d:%02d.%03d", ms/60000, ms/1000%60, ms%1000)
+}
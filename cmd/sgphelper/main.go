@@ -1,22 +1,316 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
 	sgphelper "sgpHelper"
 	"sgpHelper/config"
 	"sgpHelper/racedata"
+	"strings"
+	"syscall"
+	"time"
 )
 
+// SHUTDOWN_TIMEOUT max time to wait for running requests on shutdown
+const SHUTDOWN_TIMEOUT = 30 * time.Second
+
+const usage = `usage:
+  sgphelper [--config file]                                               start the web server
+  sgphelper [--config file] [--league slug] fetch <season> <race> <event>  download the event results into the race
+  sgphelper [--config file] config print                                  show the effective configuration
+
+config fields are overridden by environment variables, e.g. SGP_SERVER_PORT
+
+flags:
+`
+
 func main() {
 
+	configFile := flag.String("config", envOr("SGP_CONFIG", config.DEFAULT_FILE), "config file, the defaults are used if it is missing")
+	leagueFlag := flag.String("league", "", "league of the fetch command if leagues are configured")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+
 	config := config.NewConfig()
-	config.ReadFile()
-	log.Printf("%v", config)
+	if err := config.ReadFile(*configFile); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := config.ReadEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if len(args) > 0 && args[0] == "config" {
+		if len(args) != 2 || args[1] != "print" {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := config.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if err := config.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config:\n%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := config.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	logger, err := newLogger(config.Log.Level, config.Log.Format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+	logger.Info(config.String())
+
+	for name, p := range config.CSV.Profiles {
+		profile, err := racedata.NewCSVProfile(name, p.Delimiter, p.Columns)
+		if err != nil {
+			fatal("invalid csv profile", err)
+		}
+		racedata.RegisterCSVProfile(profile)
+	}
+
+	// race data of the single league or of every configured league
+	var season *racedata.RaceData
+	leagues := map[string]*racedata.RaceData{}
+	if len(config.Leagues) == 0 {
+		season = racedata.NewRaceData(config.Server.DataDir, config.Server.RaceData, logger)
+		season.SetPoints(pointsRule(config.Points))
+	}
+	for _, l := range config.Leagues {
+		slug := leagueSlug(l)
+		dataDir := path.Join(config.Server.DataDir, slug)
+		if err := os.MkdirAll(dataDir, 0770); err != nil {
+			fatal("can not create league directory", err)
+		}
+		leagues[slug] = racedata.NewRaceData(dataDir, path.Join(dataDir, path.Base(config.Server.RaceData)), logger.With("league", slug))
+		points := config.Points
+		if l.Points != nil {
+			points = *l.Points
+		}
+		leagues[slug].SetPoints(pointsRule(points))
+	}
 
-	season := racedata.NewRaceData(config.Server.DataDir, config.Server.RaceData)
+	var fetcher *racedata.Fetcher
+	if config.Fetch.URL != "" {
+		fetcher = racedata.NewFetcher(config.Fetch.URL, config.Fetch.QualySession, config.Fetch.RaceSession,
+			time.Duration(config.Fetch.Timeout)*time.Second, config.Fetch.Retries)
+	}
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "fetch":
+			if len(args) != 4 {
+				flag.Usage()
+				os.Exit(2)
+			}
+			if len(leagues) > 0 {
+				var found bool
+				if season, found = leagues[*leagueFlag]; !found {
+					fatal("fetch failed", fmt.Errorf("league %q not found, set --league", *leagueFlag))
+				}
+			}
+			if err := fetch(fetcher, season, args[1], args[2], args[3]); err != nil {
+				fatal("fetch failed", err)
+			}
+		default:
+			flag.Usage()
+			os.Exit(2)
+		}
+		return
+	}
 
 	s := sgphelper.NewServer(":"+config.Server.Port, season)
-	if err := s.Start(); err != nil {
-		log.Fatal(err)
+	s.SetLogger(logger)
+	s.SetBasePath(config.Server.BasePath)
+	s.SetTrustProxy(config.Server.TrustProxy)
+	if config.Server.TLSCert != "" {
+		s.SetTLS(config.Server.TLSCert, config.Server.TLSKey)
+	}
+	if config.Server.Theme != "" {
+		if err := s.SetTheme(config.Server.Theme); err != nil {
+			fatal("can not load theme", err)
+		}
+	}
+	s.SetTimeouts(time.Duration(config.Server.ReadTimeout)*time.Second,
+		time.Duration(config.Server.WriteTimeout)*time.Second,
+		time.Duration(config.Server.IdleTimeout)*time.Second)
+	s.SetUploadLimits(int64(config.Server.MaxUploadMB)*1024*1024, int64(config.Server.MaxArchiveMB)*1024*1024)
+
+	if len(config.Leagues) == 0 {
+		setupData(s, config, season, fetcher, config.Server.Inbox, config.Server.HookToken, nil, nil)
+	} else if auth := newAuth(config, nil, nil); auth != nil {
+		// instance admins and tokens, e.g. for the metrics
+		s.SetAuth(auth)
+	}
+	for _, l := range config.Leagues {
+		slug := leagueSlug(l)
+		ls, err := s.AddLeague(l.Name, slug, leagues[slug])
+		if err != nil {
+			fatal("invalid league", err)
+		}
+		inbox := ""
+		if config.Server.Inbox != "" {
+			inbox = path.Join(config.Server.Inbox, slug)
+		}
+		hookToken := l.HookToken
+		if hookToken == "" {
+			hookToken = config.Server.HookToken
+		}
+		setupData(ls, config, leagues[slug], fetcher, inbox, hookToken, l.Admins, l.Tokens)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopped := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		logger.Info("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+		defer cancel()
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			logger.Error("shutdown", "error", err)
+		}
+		close(stopped)
+	}()
+
+	if err := s.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatal("server stopped", err)
+	}
+	<-stopped
+}
+
+// setupData inbox, fetcher, auth and hook of the server of the race data,
+// admins and tokens are added to the instance admins and tokens
+func setupData(s *sgphelper.Server, cfg *config.Config, season *racedata.RaceData, fetcher *racedata.Fetcher, inboxDir string, hookToken string, admins []config.Admin, tokens []config.Token) {
+	if inboxDir != "" {
+		inbox := racedata.NewInbox(inboxDir, time.Duration(cfg.Server.InboxInterval)*time.Second, season)
+		inbox.Start()
+		s.SetInbox(inbox)
+	}
+
+	if fetcher != nil {
+		s.SetFetcher(fetcher)
+	}
+
+	if auth := newAuth(cfg, admins, tokens); auth != nil {
+		s.SetAuth(auth)
+	}
+
+	if hookToken != "" {
+		s.SetHookToken(hookToken)
+	}
+}
+
+// newAuth auth with the instance and the given admins and tokens, nil if disabled
+func newAuth(cfg *config.Config, admins []config.Admin, tokens []config.Token) *sgphelper.Auth {
+	if !cfg.Auth.Enabled {
+		return nil
+	}
+	auth := sgphelper.NewAuth(cfg.Auth.AnonymousRead, time.Duration(cfg.Auth.SessionHours)*time.Hour)
+	for _, a := range append(append([]config.Admin{}, cfg.Auth.Admins...), admins...) {
+		auth.AddAdmin(a.User, a.Password)
+	}
+	for _, t := range append(append([]config.Token{}, cfg.Auth.Tokens...), tokens...) {
+		role, err := sgphelper.ParseRole(t.Scope)
+		if err != nil {
+			fatal("invalid token "+t.Name, err)
+		}
+		auth.AddToken(t.Token, role)
+	}
+	return auth
+}
+
+// leagueSlug url and directory name of the league
+func leagueSlug(l config.League) string {
+	if l.Slug != "" {
+		return l.Slug
+	}
+	return racedata.Slugify(l.Name)
+}
+
+// pointsRule bonus points of the config points section
+func pointsRule(p config.Points) racedata.PointsRule {
+	return racedata.PointsRule{
+		Pole:             p.Pole,
+		FastestLap:       p.FastestLap,
+		FastestLapMaxPos: p.FastestLapMaxPos,
+		FastestCleanLap:  p.FastestCleanLap,
+	}
+}
+
+// fetch download the event results into the race, the race is created if missing
+func fetch(fetcher *racedata.Fetcher, season *racedata.RaceData, seasonName string, raceName string, eventID string) error {
+	if fetcher == nil {
+		return fmt.Errorf("no fetch url configured")
 	}
+
+	created := false
+	if _, _, err := season.GetResultFilenames(seasonName, raceName); err != nil {
+		if err := season.AddRace(seasonName, raceName); err != nil {
+			return err
+		}
+		created = true
+	}
+
+	if err := fetcher.FetchResults(season, seasonName, raceName, eventID); err != nil {
+		if created {
+			season.RemoveRace(seasonName, raceName)
+		}
+		return err
+	}
+
+	slog.Info("fetched event", "event", eventID, "season", seasonName, "race", raceName)
+	return nil
+}
+
+// newLogger slog logger writing to stderr with level debug, info, warn
+// or error and format text or json
+func newLogger(level string, format string) (*slog.Logger, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %v - %v", level, err)
+	}
+
+	options := &slog.HandlerOptions{Level: l}
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, options)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %v, use text or json", format)
+}
+
+// envOr value of the environment variable or the default if not set
+func envOr(name string, defaultValue string) string {
+	if value, found := os.LookupEnv(name); found {
+		return value
+	}
+	return defaultValue
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
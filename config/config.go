@@ -1,49 +1,385 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	Server Server `yaml:"server"`
+	CSV    CSV    `yaml:"csv"`
+	Fetch  Fetch  `yaml:"fetch"`
+	Auth   Auth   `yaml:"auth"`
+	Log    Log    `yaml:"log"`
+	Points Points `yaml:"points"`
+	// Leagues hosted below /{slug}/, a single league at / if empty
+	Leagues []League `yaml:"leagues"`
 }
 
 type Server struct {
 	Port     string `yaml:"port"`
 	DataDir  string `yaml:"dataDir"`
 	RaceData string `yaml:"raceData"`
+	// Inbox watch folder for automatic result imports, disabled if empty
+	Inbox string `yaml:"inbox"`
+	// InboxInterval inbox polling interval in seconds
+	InboxInterval int `yaml:"inboxInterval"`
+	// HookToken bearer token of the result webhook, disabled if empty
+	HookToken string `yaml:"hookToken"`
+	// ReadTimeout, WriteTimeout and IdleTimeout http timeouts in seconds
+	ReadTimeout  int `yaml:"readTimeout"`
+	WriteTimeout int `yaml:"writeTimeout"`
+	IdleTimeout  int `yaml:"idleTimeout"`
+	// MaxUploadMB max size of result and entry list uploads in MB
+	MaxUploadMB int `yaml:"maxUploadMB"`
+	// MaxArchiveMB max size of season archive imports in MB
+	MaxArchiveMB int `yaml:"maxArchiveMB"`
+	// BasePath path prefix behind a reverse proxy, e.g. /results
+	BasePath string `yaml:"basePath"`
+	// TrustProxy honour X-Forwarded-For, -Proto, -Host and -Prefix headers
+	TrustProxy bool `yaml:"trustProxy"`
+	// TLSCert and TLSKey serve https directly if set
+	TLSCert string `yaml:"tlsCert"`
+	TLSKey  string `yaml:"tlsKey"`
+	// Theme directory with templates/ and public/ files overriding the built-in ones
+	Theme string `yaml:"theme"`
+}
+
+// CSV header mapping profiles for csv uploads
+type CSV struct {
+	Profiles map[string]CSVProfile `yaml:"profiles"`
+}
+
+// CSVProfile source column -> field mapping, the delimiter is
+// detected from the header line if empty
+type CSVProfile struct {
+	Delimiter string            `yaml:"delimiter"`
+	Columns   map[string]string `yaml:"columns"`
+}
+
+// Fetch result download from a simracing.gp style http endpoint, the url
+// contains the placeholders {event} and {session}, disabled if empty
+type Fetch struct {
+	URL          string `yaml:"url"`
+	QualySession string `yaml:"qualySession"`
+	RaceSession  string `yaml:"raceSession"`
+	// Timeout per request in seconds
+	Timeout int `yaml:"timeout"`
+	Retries int `yaml:"retries"`
+}
+
+// Points championship bonus points for pole position, fastest lap and
+// fastest clean lap, the fastest lap only counts for cars finishing at
+// fastestLapMaxPos or better if set
+type Points struct {
+	Pole             int `yaml:"pole"`
+	FastestLap       int `yaml:"fastestLap"`
+	FastestLapMaxPos int `yaml:"fastestLapMaxPos"`
+	FastestCleanLap  int `yaml:"fastestCleanLap"`
+}
+
+func (p Points) valid() bool {
+	return p.Pole >= 0 && p.FastestLap >= 0 && p.FastestLapMaxPos >= 0 && p.FastestCleanLap >= 0
+}
+
+// Log level debug, info, warn or error and format text or json
+type Log struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// Auth admin logins and api tokens, everybody is admin if disabled
+type Auth struct {
+	Enabled bool `yaml:"enabled"`
+	// AnonymousRead results, entry lists and standings are public
+	AnonymousRead bool `yaml:"anonymousRead"`
+	// SessionHours lifetime of admin login sessions
+	SessionHours int     `yaml:"sessionHours"`
+	Admins       []Admin `yaml:"admins"`
+	Tokens       []Token `yaml:"tokens"`
+}
+
+type Admin struct {
+	User string `yaml:"user"`
+	// Password plain text or bcrypt hash
+	Password string `yaml:"password"`
+}
+
+// League own seasons in dataDir/<slug>, own branding and admins, the
+// admins and tokens of auth are admins of every league
+type League struct {
+	Name string `yaml:"name"`
+	// Slug url and directory name, derived from the name if empty
+	Slug string `yaml:"slug"`
+	// HookToken result webhook token, server.hookToken if empty
+	HookToken string  `yaml:"hookToken"`
+	Admins    []Admin `yaml:"admins"`
+	Tokens    []Token `yaml:"tokens"`
+	// Points bonus points of the league, the points section if not set
+	Points *Points `yaml:"points,omitempty"`
+}
+
+// Token api token with scope read, steward or admin
+type Token struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	Scope string `yaml:"scope"`
 }
 
 func (c Config) String() string {
-	return fmt.Sprintf("config server port: %v data dir: %v race data file: %v\n",
-		c.Server.Port, c.Server.DataDir, c.Server.RaceData)
+	return fmt.Sprintf("config server port: %v data dir: %v race data file: %v inbox: %v csv profiles: %v fetch url: %v auth: %v log: %v/%v leagues: %v",
+		c.Server.Port, c.Server.DataDir, c.Server.RaceData, c.Server.Inbox, len(c.CSV.Profiles), c.Fetch.URL, c.Auth.Enabled, c.Log.Level, c.Log.Format, len(c.Leagues))
 }
 
 // NewConfig create new default config
 func NewConfig() *Config {
 	return &Config{
 		Server: Server{
-			Port:     "8080",
-			DataDir:  "data",
-			RaceData: "race_data.json",
+			Port:          "8080",
+			DataDir:       "data",
+			RaceData:      "race_data.json",
+			InboxInterval: 30,
+			ReadTimeout:   60,
+			WriteTimeout:  120,
+			IdleTimeout:   120,
+			MaxUploadMB:   1,
+			MaxArchiveMB:  20,
+		},
+		Fetch: Fetch{
+			QualySession: "qualifying",
+			RaceSession:  "race",
+			Timeout:      30,
+			Retries:      2,
+		},
+		Auth: Auth{
+			AnonymousRead: true,
+			SessionHours:  24,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "text",
 		},
 	}
 }
 
-// ReadFile read config yml file in default root location
-func (c *Config) ReadFile() {
-	f, err := os.Open("config.yml")
+// DEFAULT_FILE config file used without --config flag
+const DEFAULT_FILE = "config.yml"
+
+// ENV_PREFIX prefix of environment variables overriding config fields
+const ENV_PREFIX = "SGP"
+
+// ReadFile read the config yml file, a missing file keeps the defaults
+func (c *Config) ReadFile(filename string) error {
+	f, err := os.Open(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		log.Panic(err)
+		return err
 	}
 	defer f.Close()
 
-	err = yaml.NewDecoder(f).Decode(c)
+	if err := yaml.NewDecoder(f).Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("config file %v - %v", filename, err)
+	}
+	return nil
+}
+
+// ReadEnv override fields with environment variables named by prefix,
+// section and yaml key, e.g. SGP_SERVER_PORT or SGP_FETCH_QUALY_SESSION.
+// Lists and maps are given as yaml, e.g. SGP_AUTH_ADMINS='[{user: a, password: b}]'
+func (c *Config) ReadEnv() error {
+	return readEnv(reflect.ValueOf(c).Elem(), ENV_PREFIX)
+}
+
+func readEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("yaml")
+		if key == "" {
+			continue
+		}
+		name := prefix + "_" + envName(key)
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			if err := readEnv(field, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, found := os.LookupEnv(name)
+		if !found {
+			continue
+		}
+		if field.Kind() == reflect.String {
+			field.SetString(value)
+			continue
+		}
+		if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
+			return fmt.Errorf("environment variable %v - %v", name, err)
+		}
+	}
+	return nil
+}
+
+// envName upper case snake case of a yaml key, e.g. maxUploadMB -> MAX_UPLOAD_MB
+func envName(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && !unicode.IsUpper(runes[i-1]) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// Validate check the config, all problems are reported together
+func (c *Config) Validate() error {
+	errs := []error{}
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	port, err := strconv.Atoi(c.Server.Port)
+	check(err == nil && port >= 1 && port <= 65535, "server.port %q must be a number between 1 and 65535", c.Server.Port)
+	check(c.Server.RaceData != "", "server.raceData must not be empty")
+	if c.Server.DataDir == "" {
+		check(false, "server.dataDir must not be empty")
+	} else if err := checkWritable(c.Server.DataDir); err != nil {
+		check(false, "server.dataDir %v is not writable - %v", c.Server.DataDir, err)
+	}
+	check(c.Server.Inbox == "" || c.Server.InboxInterval > 0, "server.inboxInterval must be greater than 0")
+	check(c.Server.ReadTimeout >= 0 && c.Server.WriteTimeout >= 0 && c.Server.IdleTimeout >= 0, "server timeouts must not be negative")
+	check(c.Server.MaxUploadMB > 0, "server.maxUploadMB must be greater than 0")
+	check(c.Server.MaxArchiveMB > 0, "server.maxArchiveMB must be greater than 0")
+	check(!strings.ContainsAny(c.Server.BasePath, "?#{} "), "server.basePath %q must be a plain path", c.Server.BasePath)
+	check((c.Server.TLSCert == "") == (c.Server.TLSKey == ""), "server.tlsCert and server.tlsKey must be set together")
+	for _, file := range []string{c.Server.TLSCert, c.Server.TLSKey} {
+		if file != "" {
+			_, err := os.Stat(file)
+			check(err == nil, "tls file %v - %v", file, err)
+		}
+	}
+	if c.Server.Theme != "" {
+		info, err := os.Stat(c.Server.Theme)
+		check(err == nil && info.IsDir(), "server.theme %v must be a directory", c.Server.Theme)
+	}
+
+	if c.Fetch.URL != "" {
+		check(strings.Contains(c.Fetch.URL, "{event}"), "fetch.url must contain the {event} placeholder")
+		check(c.Fetch.Timeout > 0, "fetch.timeout must be greater than 0")
+		check(c.Fetch.Retries >= 0, "fetch.retries must not be negative")
+	}
+
+	if c.Auth.Enabled {
+		check(c.Auth.SessionHours > 0, "auth.sessionHours must be greater than 0")
+		checkAdmins("auth", c.Auth.Admins, c.Auth.Tokens, check)
+	}
+
+	slugs := map[string]bool{}
+	for i, l := range c.Leagues {
+		check(strings.TrimSpace(l.Name) != "", "leagues[%v] needs a name", i)
+		if l.Slug != "" {
+			check(slugPattern.MatchString(l.Slug), "leagues[%v].slug %q must only contain a-z, 0-9 and -", i, l.Slug)
+			check(!slugs[l.Slug], "leagues[%v].slug %q is not unique", i, l.Slug)
+			slugs[l.Slug] = true
+		}
+		if c.Auth.Enabled {
+			checkAdmins(fmt.Sprintf("leagues[%v]", i), l.Admins, l.Tokens, check)
+		}
+		if l.Points != nil {
+			check(l.Points.valid(), "leagues[%v].points must not be negative", i)
+		}
+	}
+
+	check(c.Points.valid(), "points must not be negative")
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(c.Log.Level)) == nil, "log.level %q must be debug, info, warn or error", c.Log.Level)
+	format := strings.ToLower(c.Log.Format)
+	check(format == "" || format == "text" || format == "json", "log.format %q must be text or json", c.Log.Format)
+
+	return errors.Join(errs...)
+}
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+func checkAdmins(section string, admins []Admin, tokens []Token, check func(bool, string, ...any)) {
+	for _, a := range admins {
+		check(a.User != "" && a.Password != "", "%v.admins need user and password", section)
+	}
+	for _, t := range tokens {
+		check(t.Token != "", "%v.tokens %v has no token", section, t.Name)
+		scope := strings.ToLower(t.Scope)
+		check(scope == "read" || scope == "steward" || scope == "admin", "%v.tokens %v scope %q must be read, steward or admin", section, t.Name, t.Scope)
+	}
+}
+
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0770); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".configcheck-*")
 	if err != nil {
-		log.Panic(err)
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// Print write the effective config as yaml, passwords and tokens are masked
+func (c Config) Print(w io.Writer) error {
+	masked := c
+	mask := func(secret string) string {
+		if secret == "" {
+			return ""
+		}
+		return "********"
+	}
+	maskAdmins := func(admins []Admin) []Admin {
+		masked := make([]Admin, len(admins))
+		for i, a := range admins {
+			masked[i] = Admin{User: a.User, Password: mask(a.Password)}
+		}
+		return masked
+	}
+	maskTokens := func(tokens []Token) []Token {
+		masked := make([]Token, len(tokens))
+		for i, t := range tokens {
+			masked[i] = Token{Name: t.Name, Token: mask(t.Token), Scope: t.Scope}
+		}
+		return masked
+	}
+	masked.Server.HookToken = mask(c.Server.HookToken)
+	masked.Auth.Admins = maskAdmins(c.Auth.Admins)
+	masked.Auth.Tokens = maskTokens(c.Auth.Tokens)
+	masked.Leagues = make([]League, len(c.Leagues))
+	for i, l := range c.Leagues {
+		masked.Leagues[i] = League{Name: l.Name, Slug: l.Slug, HookToken: mask(l.HookToken),
+			Admins: maskAdmins(l.Admins), Tokens: maskTokens(l.Tokens), Points: l.Points}
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(masked); err != nil {
+		return err
 	}
+	return encoder.Close()
 }
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfigFile = `server:
+  port: 9000
+  dataDir: league-data
+  maxUploadMB: 5
+fetch:
+  qualySession: quali
+auth:
+  enabled: true
+  admins:
+    - user: file-admin
+      password: file-secret
+points:
+  pole: 3
+`
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), DEFAULT_FILE)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestReadFileAndEnv(t *testing.T) {
+	t.Setenv("SGP_SERVER_PORT", "9090")
+	t.Setenv("SGP_SERVER_TRUST_PROXY", "true")
+	t.Setenv("SGP_AUTH_ADMINS", "[{user: env-admin, password: env-secret}]")
+	t.Setenv("SGP_POINTS_FASTEST_LAP_MAX_POS", "10")
+
+	c := NewConfig()
+	if err := c.ReadFile(writeConfig(t, testConfigFile)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ReadEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		field string
+		got   any
+		want  any
+	}{
+		// environment over file over defaults
+		{"server.port", c.Server.Port, "9090"},
+		{"server.trustProxy", c.Server.TrustProxy, true},
+		{"auth.admins", len(c.Auth.Admins) == 1 && c.Auth.Admins[0].User == "env-admin", true},
+		{"points.fastestLapMaxPos", c.Points.FastestLapMaxPos, 10},
+		{"server.dataDir", c.Server.DataDir, "league-data"},
+		{"server.maxUploadMB", c.Server.MaxUploadMB, 5},
+		{"fetch.qualySession", c.Fetch.QualySession, "quali"},
+		{"points.pole", c.Points.Pole, 3},
+		{"fetch.raceSession", c.Fetch.RaceSession, "race"},
+		{"server.maxArchiveMB", c.Server.MaxArchiveMB, 20},
+		{"auth.anonymousRead", c.Auth.AnonymousRead, true},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%v = %v, want %v", tc.field, tc.got, tc.want)
+		}
+	}
+}
+
+func TestReadFileErrors(t *testing.T) {
+	c := NewConfig()
+	if err := c.ReadFile(filepath.Join(t.TempDir(), "missing.yml")); err != nil {
+		t.Errorf("missing file: %v", err)
+	}
+	if c.Server.Port != "8080" {
+		t.Errorf("defaults changed by a missing file, port %v", c.Server.Port)
+	}
+	if err := c.ReadFile(writeConfig(t, "")); err != nil {
+		t.Errorf("empty file: %v", err)
+	}
+	if err := c.ReadFile(writeConfig(t, "server: [port]\n")); err == nil {
+		t.Error("invalid yaml was accepted")
+	}
+}
+
+func TestReadEnvErrors(t *testing.T) {
+	t.Setenv("SGP_SERVER_MAX_UPLOAD_MB", "ten")
+
+	err := NewConfig().ReadEnv()
+	if err == nil || !strings.Contains(err.Error(), "SGP_SERVER_MAX_UPLOAD_MB") {
+		t.Errorf("error %v, want the variable name", err)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for key, want := range map[string]string{
+		"port":             "PORT",
+		"dataDir":          "DATA_DIR",
+		"maxUploadMB":      "MAX_UPLOAD_MB",
+		"fastestLapMaxPos": "FASTEST_LAP_MAX_POS",
+		"tlsCert":          "TLS_CERT",
+		"url":              "URL",
+	} {
+		if got := envName(key); got != want {
+			t.Errorf("envName(%v) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dataDir := t.TempDir()
+
+	for _, tc := range []struct {
+		name   string
+		change func(c *Config)
+		errs   []string
+	}{
+		{"defaults", func(c *Config) {}, nil},
+		{"port", func(c *Config) { c.Server.Port = "http" }, []string{"server.port"}},
+		{"port range", func(c *Config) { c.Server.Port = "70000" }, []string{"server.port"}},
+		{"data dir", func(c *Config) { c.Server.DataDir = "" }, []string{"server.dataDir"}},
+		{"not writable", func(c *Config) {
+			file := filepath.Join(dataDir, "file")
+			os.WriteFile(file, nil, 0644)
+			c.Server.DataDir = filepath.Join(file, "data")
+		}, []string{"not writable"}},
+		{"inbox interval", func(c *Config) { c.Server.Inbox = "inbox"; c.Server.InboxInterval = 0 }, []string{"server.inboxInterval"}},
+		{"upload size", func(c *Config) { c.Server.MaxUploadMB = 0; c.Server.MaxArchiveMB = -1 }, []string{"server.maxUploadMB", "server.maxArchiveMB"}},
+		{"base path", func(c *Config) { c.Server.BasePath = "/results?x" }, []string{"server.basePath"}},
+		{"tls pair", func(c *Config) { c.Server.TLSCert = "cert.pem" }, []string{"tlsCert and server.tlsKey", "tls file cert.pem"}},
+		{"theme", func(c *Config) { c.Server.Theme = filepath.Join(dataDir, "missing") }, []string{"server.theme"}},
+		{"fetch url", func(c *Config) { c.Fetch.URL = "https://results.example/"; c.Fetch.Retries = -1 }, []string{"{event}", "fetch.retries"}},
+		{"auth", func(c *Config) {
+			c.Auth.Enabled = true
+			c.Auth.Admins = []Admin{{User: "admin"}}
+			c.Auth.Tokens = []Token{{Name: "bot", Token: "t", Scope: "owner"}}
+		}, []string{"auth.admins need user and password", "auth.tokens bot scope"}},
+		{"league slugs", func(c *Config) {
+			c.Leagues = []League{{Name: "A", Slug: "gt"}, {Name: "B", Slug: "gt"}, {Name: " ", Slug: "Formula 1"}}
+		}, []string{"leagues[1].slug \"gt\" is not unique", "leagues[2] needs a name", "leagues[2].slug"}},
+		{"points", func(c *Config) {
+			c.Points.Pole = -1
+			c.Leagues = []League{{Name: "A", Points: &Points{FastestLap: -1}}}
+		}, []string{"leagues[0].points", "points must not be negative"}},
+		{"log", func(c *Config) { c.Log.Level = "verbose"; c.Log.Format = "xml" }, []string{"log.level", "log.format"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewConfig()
+			c.Server.DataDir = dataDir
+			tc.change(c)
+
+			err := c.Validate()
+			if len(tc.errs) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want %v", tc.errs)
+			}
+			// all problems are reported together, one per line
+			if lines := strings.Count(err.Error(), "\n") + 1; lines != len(tc.errs) {
+				t.Errorf("%v errors, want %v: %v", lines, len(tc.errs), err)
+			}
+			for _, want := range tc.errs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %v, want %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPrintMasksSecrets(t *testing.T) {
+	c := NewConfig()
+	c.Server.HookToken = "hook-secret"
+	c.Auth.Admins = []Admin{{User: "admin", Password: "admin-secret"}}
+	c.Auth.Tokens = []Token{{Name: "bot", Token: "token-secret", Scope: "read"}}
+	c.Leagues = []League{{Name: "GT", HookToken: "league-secret", Admins: []Admin{{User: "gt", Password: "gt-secret"}}}}
+
+	buf := &bytes.Buffer{}
+	if err := c.Print(buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("secrets in printed config:\n%v", buf.String())
+	}
+	if !strings.Contains(buf.String(), "user: gt") {
+		t.Errorf("league admin missing:\n%v", buf.String())
+	}
+	if c.Auth.Admins[0].Password != "admin-secret" {
+		t.Error("printing changed the config")
+	}
+}
@@ -0,0 +1,127 @@
+package sgphelper
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// CSRF_COOKIE name of the cookie holding the csrf token of the browser
+const CSRF_COOKIE = "sgp_csrf"
+
+// CSRF_FIELD name of the hidden form field with the csrf token
+const CSRF_FIELD = "csrf_token"
+
+// secure add security headers to every reply, reject paths with dot
+// segments and make sure the browser has a csrf token, a new token is
+// also visible to the current request
+func (s *Server) secure(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("Referrer-Policy", "same-origin")
+		header.Set("Content-Security-Policy", "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; frame-ancestors 'none'; form-action 'self'")
+
+		if hasDotSegment(r.URL.Path) {
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+
+		if cookie, err := r.Cookie(CSRF_COOKIE); err != nil || cookie.Value == "" {
+			token, err := newCSRFToken()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			http.SetCookie(w, &http.Cookie{
+				Name:     CSRF_COOKIE,
+				Value:    token,
+				Path:     cookiePath(r),
+				HttpOnly: true,
+				Secure:   isHTTPS(r),
+				SameSite: http.SameSiteStrictMode,
+			})
+			r = r.Clone(r.Context())
+			r.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: token})
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// hasDotSegment path contains . or .. segments, also percent-encoded ones
+// which are not cleaned by the mux
+func hasDotSegment(path string) bool {
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "." || segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// form limit the body of form posts to size bytes, parse the form and
+// reject it if the csrf token does not match the cookie of the browser
+func (s *Server) form(size int64, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" || r.Method == "HEAD" {
+			h(w, r)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, size)
+
+		var err error
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "multipart/form-data" {
+			err = r.ParseMultipartForm(size)
+		} else {
+			err = r.ParseForm()
+		}
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "The upload is too big. Please choose files less than "+formatSize(size)+" in size", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !validCSRFToken(r) {
+			requestLogger(r).Warn("invalid csrf token", "method", r.Method, "path", r.URL.Path)
+			http.Error(w, "invalid csrf token, please reload the page and try again", http.StatusForbidden)
+			return
+		}
+
+		h(w, r)
+	}
+}
+
+// csrfToken csrf token of the browser for the forms of the page
+func csrfToken(r *http.Request) string {
+	cookie, err := r.Cookie(CSRF_COOKIE)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+func validCSRFToken(r *http.Request) bool {
+	expected := csrfToken(r)
+	token := r.PostFormValue(CSRF_FIELD)
+	return expected != "" && subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,128 @@
+package sgphelper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// formRoutes every route accepting form posts
+var formRoutes = []string{
+	"/login",
+	"/logout",
+	"/delete/season-1/race-1",
+	"/addPenalty/season-1/race-1",
+	"/fetch/season-1/race-1",
+	"/newSeason",
+	"/importSeason",
+	"/upload/season-1",
+	"/laps/season-1/race-1",
+	"/uploadEntryList/season-1",
+	"/drivers/merge",
+	"/drivers/anna-fast",
+	"/branding",
+	"/branding/season-1",
+}
+
+func TestCSRFMissingToken(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, withCookie := range []bool{false, true} {
+		r := httptest.NewRequest("POST", "/newSeason", strings.NewReader(url.Values{"new_season_name": {"Season 2"}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if withCookie {
+			r.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: testCSRFToken})
+		}
+
+		if w := ts.serve(r); w.Code != http.StatusForbidden {
+			t.Errorf("cookie %v: status %v, want %v", withCookie, w.Code, http.StatusForbidden)
+		}
+	}
+	if _, found := ts.data.Seasons["Season 2"]; found {
+		t.Error("season was created without csrf token")
+	}
+}
+
+func TestCSRFTokenMismatch(t *testing.T) {
+	ts := newTestServer(t)
+
+	r := formRequest("/newSeason", url.Values{"new_season_name": {"Season 2"}})
+	r.Header.Del("Cookie")
+	r.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: "other-token"})
+
+	if w := ts.serve(r); w.Code != http.StatusForbidden {
+		t.Errorf("status %v, want %v", w.Code, http.StatusForbidden)
+	}
+	if _, found := ts.data.Seasons["Season 2"]; found {
+		t.Error("season was created with a foreign csrf token")
+	}
+}
+
+// TestCSRFCrossSite a foreign page can make the browser send the cookie but
+// does not know the token
+func TestCSRFCrossSite(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, route := range formRoutes {
+		for _, field := range []string{"", "guessed-token"} {
+			t.Run(route+" "+field, func(t *testing.T) {
+				form := url.Values{"new_season_name": {"Season 2"}, "pos": {"1"}, "penalty": {"5"}}
+				if field != "" {
+					form.Set(CSRF_FIELD, field)
+				}
+				r := httptest.NewRequest("POST", route, strings.NewReader(form.Encode()))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+				r.Header.Set("Origin", "https://evil.example")
+				r.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: testCSRFToken})
+
+				if w := ts.serve(r); w.Code != http.StatusForbidden {
+					t.Errorf("status %v, want %v: %v", w.Code, http.StatusForbidden, w.Body.String())
+				}
+			})
+		}
+	}
+
+	season := ts.data.Seasons["Season 1"]
+	if len(ts.data.Seasons) != 1 || len(season.Races) != 1 {
+		t.Errorf("race data was modified by cross-site posts: %+v", ts.data.Seasons)
+	}
+}
+
+func TestCSRFValidToken(t *testing.T) {
+	ts := newTestServer(t)
+
+	w := ts.serve(formRequest("/newSeason", url.Values{"new_season_name": {"Season 2"}}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %v, want %v: %v", w.Code, http.StatusOK, w.Body.String())
+	}
+	if _, found := ts.data.Seasons["Season 2"]; !found {
+		t.Error("season was not created")
+	}
+}
+
+func TestUploadTooLarge(t *testing.T) {
+	ts := newTestServer(t)
+	ts.SetUploadLimits(1024, 2048)
+
+	for _, tc := range []struct {
+		path string
+		file string
+		size int
+	}{
+		{"/upload/season-1", "race_result", 4096},
+		{"/importSeason", "season_archive", 8192},
+	} {
+		body, contentType := multipartForm(t,
+			map[string]string{CSRF_FIELD: testCSRFToken, "new_race_name": "Race 2", "season_name": "Season 2"},
+			map[string]string{tc.file: strings.Repeat("x", tc.size)})
+		r := httptest.NewRequest("POST", tc.path, body)
+		r.Header.Set("Content-Type", contentType)
+		r.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: testCSRFToken})
+
+		if w := ts.serve(r); w.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%v: status %v, want %v", tc.path, w.Code, http.StatusRequestEntityTooLarge)
+		}
+	}
+}
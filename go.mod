@@ -4,5 +4,6 @@ go 1.22.0
 
 require (
 	github.com/artyom/csvstruct v1.1.0
+	golang.org/x/crypto v0.31.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,154 @@
+package sgphelper
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"sgpHelper/racedata"
+)
+
+// resultHookPayload json body of the result webhook, the results are
+// the file contents in the given format, sgp if empty
+type resultHookPayload struct {
+	Format string `json:"format"`
+	Qualy  string `json:"qualy"`
+	Race   string `json:"race"`
+}
+
+// SetHookToken enable the result webhook, requests must send the
+// token or an api token with steward scope as bearer authorization
+func (s *Server) SetHookToken(token string) {
+	s.hookToken = token
+}
+
+// handleResultHook add qualy and race results pushed as json or multipart
+// form to the race, the race is created if missing. The reply is the
+// validation report as json, 400 if the results are not valid and 409
+// if the season has no entry list yet
+func (s *Server) handleResultHook(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	raceName := r.PathValue("race")
+	requestLogger(r).Info("result hook", "season", seasonName, "race", raceName)
+
+	if s.hookToken == "" && s.auth == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("result webhook is disabled"))
+		return
+	}
+
+	if r.Method != "POST" {
+		writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+		return
+	}
+
+	if !s.isHookAuthorized(r) {
+		writeAPIError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing token"))
+		return
+	}
+
+	payload, err := readResultHookPayload(w, r, s.maxUploadSize*2)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report := racedata.ValidateResults(payload.Format, []byte(payload.Qualy), []byte(payload.Race))
+	report.Season = seasonName
+	report.Race = raceName
+	if !report.Ok {
+		writeJSON(w, http.StatusBadRequest, report)
+		return
+	}
+
+	s.season.Lock()
+	defer s.season.Unlock()
+	s.resolveNames(r)
+	seasonName = r.PathValue("season")
+	raceName = r.PathValue("race")
+
+	entryList, err := s.season.GetEntryListFilename(seasonName)
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, err)
+		return
+	}
+	// results can only be matched to teams and drivers with an entry list
+	if _, err := os.Stat(entryList); entryList == "" || err != nil {
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("season %v has no entry list", seasonName))
+		return
+	}
+
+	created := false
+	if _, _, err := s.season.GetResultFilenames(seasonName, raceName); err != nil {
+		if err := s.season.AddRace(seasonName, raceName); err != nil {
+			writeAPIError(w, apiErrorStatus(err), err)
+			return
+		}
+		created = true
+	}
+
+	if err := s.season.ImportResults(seasonName, raceName, payload.Format, []byte(payload.Qualy), []byte(payload.Race)); err != nil {
+		if created {
+			s.season.RemoveRace(seasonName, raceName)
+		}
+		writeAPIError(w, apiErrorStatus(err), err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (s *Server) isHookAuthorized(r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return false
+	}
+	token = strings.TrimSpace(token)
+	if s.auth != nil && s.auth.tokenRole(token) >= RoleSteward {
+		return true
+	}
+	return s.hookToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.hookToken)) == 1
+}
+
+func readResultHookPayload(w http.ResponseWriter, r *http.Request, size int64) (*resultHookPayload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, size)
+
+	payload := &resultHookPayload{}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+			return nil, err
+		}
+		return payload, nil
+	}
+
+	if err := r.ParseMultipartForm(size); err != nil {
+		return nil, err
+	}
+	payload.Format = r.PostFormValue("result_format")
+
+	qualy, err := readFormFile(r, "qualy_result")
+	if err != nil {
+		return nil, err
+	}
+	race, err := readFormFile(r, "race_result")
+	if err != nil {
+		return nil, err
+	}
+	payload.Qualy = string(qualy)
+	payload.Race = string(race)
+	return payload, nil
+}
+
+func readFormFile(r *http.Request, name string) ([]byte, error) {
+	file, _, err := r.FormFile(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
@@ -0,0 +1,98 @@
+package sgphelper
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func hookRequest(t *testing.T, path string, token string, payload resultHookPayload) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/api/hooks/results/"+path, strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestResultHookDisabled(t *testing.T) {
+	ts := newTestServer(t)
+
+	w := ts.serve(hookRequest(t, "season-1/race-1", "hook-token", resultHookPayload{Qualy: testResult, Race: testResult}))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status %v, want %v", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestResultHook(t *testing.T) {
+	ts := newTestServer(t)
+	ts.SetHookToken("hook-token")
+	if err := ts.data.AddSeason("Season 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	results := resultHookPayload{Qualy: testResult, Race: testResult}
+	for _, tc := range []struct {
+		name    string
+		path    string
+		token   string
+		payload resultHookPayload
+		status  int
+	}{
+		{"no token", "season-1/race-1", "", results, http.StatusUnauthorized},
+		{"wrong token", "season-1/race-1", "other-token", results, http.StatusUnauthorized},
+		{"existing race", "season-1/race-1", "hook-token", results, http.StatusOK},
+		{"missing race is created", "season-1/Race2", "hook-token", results, http.StatusOK},
+		{"missing season", "season-3/race-1", "hook-token", results, http.StatusNotFound},
+		{"no entry list", "season-2/race-1", "hook-token", results, http.StatusConflict},
+		{"bad csv", "season-1/race-1", "hook-token", resultHookPayload{Qualy: testResult, Race: "pos;team\n1;a\n"}, http.StatusBadRequest},
+		{"unknown format", "season-1/race-1", "hook-token", resultHookPayload{Format: "gpl", Qualy: testResult, Race: testResult}, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := ts.serve(hookRequest(t, tc.path, tc.token, tc.payload))
+			if w.Code != tc.status {
+				t.Errorf("status %v, want %v: %v", w.Code, tc.status, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+				t.Errorf("content type %v, want json", got)
+			}
+		})
+	}
+
+	if _, _, err := ts.data.GetResultFilenames("Season 1", "Race2"); err != nil {
+		t.Errorf("race of the hook was not created: %v", err)
+	}
+	if len(ts.data.Seasons["Season 2"].Races) != 0 {
+		t.Errorf("race was created in a season without entry list")
+	}
+}
+
+func TestResultHookAPIToken(t *testing.T) {
+	ts := newTestServer(t)
+	auth := NewAuth(false, time.Hour)
+	auth.AddToken("read-token", RoleRead)
+	auth.AddToken("steward-token", RoleSteward)
+	ts.SetAuth(auth)
+
+	results := resultHookPayload{Qualy: testResult, Race: testResult}
+	for _, tc := range []struct {
+		token  string
+		status int
+	}{
+		{"read-token", http.StatusUnauthorized},
+		{"steward-token", http.StatusOK},
+	} {
+		w := ts.serve(hookRequest(t, "season-1/race-1", tc.token, results))
+		if w.Code != tc.status {
+			t.Errorf("%v: status %v, want %v: %v", tc.token, w.Code, tc.status, w.Body.String())
+		}
+	}
+}
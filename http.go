@@ -1,83 +1,280 @@
 package sgphelper
 
 import (
+	"context"
 	"embed"
-	"html/template"
+	"errors"
+	"fmt"
 	"io"
-	"log"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"sgpHelper/racedata"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
-const MAX_UPLOAD_SIZE = 1024 * 1024 // 1MB
+const DEFAULT_MAX_UPLOAD_SIZE = 1024 * 1024       // 1MB
+const DEFAULT_MAX_ARCHIVE_SIZE = 20 * 1024 * 1024 // 20MB
+
+const DEFAULT_READ_TIMEOUT = 60 * time.Second
+const DEFAULT_WRITE_TIMEOUT = 120 * time.Second
+const DEFAULT_IDLE_TIMEOUT = 120 * time.Second
 
 type Server struct {
-	server *http.Server
-	season *racedata.RaceData
+	server    *http.Server
+	season    *racedata.RaceData
+	inbox     *racedata.Inbox
+	fetcher   *racedata.Fetcher
+	hookToken string
+	auth      *Auth
+	logger    *slog.Logger
+	metrics   *metrics
+
+	// leagues hosted below /{league}/, the race data of the
+	// server itself is used if empty
+	leagues []*league
+
+	// templates and static page templates and assets, overridden by SetTheme
+	templates templates
+	static    fs.FS
+
+	basePath   string
+	trustProxy bool
+	tlsCert    string
+	tlsKey     string
+
+	// shuttingDown set by Shutdown, readyz fails from then on
+	shuttingDown atomic.Bool
+
+	maxUploadSize  int64
+	maxArchiveSize int64
 }
 
 var funcMap = map[string]interface{}{
 	"add": func(a, b int) int {
 		return a + b
 	},
+	"positionChart": positionChart,
+	"lapTimeChart":  lapTimeChart,
 }
 
-var indexTmpl = template.Must(template.New("funcMap").Funcs(funcMap).ParseFiles("templates/index.html"))
-var raceTmpl = template.Must(template.New("funcMap").Funcs(funcMap).ParseFiles("templates/race.html"))
-var entrylistTmpl = template.Must(template.New("funcMap").Funcs(funcMap).ParseFiles("templates/entrylist.html"))
-
 //go:embed public/*
 var publicFS embed.FS
 
 func NewServer(addr string, s *racedata.RaceData) *Server {
 	return &Server{
-		server: &http.Server{Addr: addr},
-		season: s,
+		server: &http.Server{
+			Addr:              addr,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       DEFAULT_READ_TIMEOUT,
+			WriteTimeout:      DEFAULT_WRITE_TIMEOUT,
+			IdleTimeout:       DEFAULT_IDLE_TIMEOUT,
+		},
+		season:         s,
+		logger:         slog.Default(),
+		metrics:        newMetrics(),
+		templates:      defaultTemplates,
+		static:         mustSub(publicFS, "public"),
+		maxUploadSize:  DEFAULT_MAX_UPLOAD_SIZE,
+		maxArchiveSize: DEFAULT_MAX_ARCHIVE_SIZE,
+	}
+}
+
+// SetTimeouts http read, write and idle timeouts, zero keeps the default
+func (s *Server) SetTimeouts(read time.Duration, write time.Duration, idle time.Duration) {
+	if read > 0 {
+		s.server.ReadTimeout = read
+	}
+	if write > 0 {
+		s.server.WriteTimeout = write
+	}
+	if idle > 0 {
+		s.server.IdleTimeout = idle
+	}
+}
+
+// SetUploadLimits max size in bytes of result and entry list uploads
+// and of season archives, zero keeps the default
+func (s *Server) SetUploadLimits(upload int64, archive int64) {
+	if upload > 0 {
+		s.maxUploadSize = upload
+	}
+	if archive > 0 {
+		s.maxArchiveSize = archive
 	}
 }
 
+// SetInbox show the import events of the inbox on the index page
+func (s *Server) SetInbox(inbox *racedata.Inbox) {
+	s.inbox = inbox
+}
+
+// SetFetcher allow downloading race results from the race page
+func (s *Server) SetFetcher(fetcher *racedata.Fetcher) {
+	s.fetcher = fetcher
+}
+
 func (s *Server) Start() error {
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", s.handleIndex)
-	mux.HandleFunc("/show/{season}/{race}", s.handleShowRace)
-	mux.HandleFunc("/delete/{season}/{race}", s.handleDeleteRace)
-	mux.HandleFunc("/addPenalty/{season}/{race}", s.handleAddPenalty)
-	mux.HandleFunc("/newSeason", s.handleNewSeason)
-	mux.HandleFunc("/upload/{season}", s.handleUpload)
-	mux.HandleFunc("/export/csv/{season}/{race}/{split}", s.handleExportRace)
-	mux.HandleFunc("/uploadEntryList/{season}", s.handleUploadEntyList)
-	mux.HandleFunc("/showEntryList/*", s.handleShowEntyList)
-	mux.Handle("/public/", http.FileServer(http.FS(publicFS)))
-	s.server.Handler = mux
-
-	log.Println("server address: ", s.server.Addr)
+	s.server.Handler = s.handler()
+	s.server.ErrorLog = slog.NewLogLogger(s.logger.Handler(), slog.LevelWarn)
 
+	s.logger.Info("start server", "address", s.server.Addr, "base_path", s.basePath, "tls", s.tlsCert != "", "leagues", len(s.leagues))
+
+	if s.tlsCert != "" {
+		return s.server.ListenAndServeTLS(s.tlsCert, s.tlsKey)
+	}
 	return s.server.ListenAndServe()
 
 }
 
+// Shutdown stop accepting requests and wait for running requests and the
+// inbox, the race data lock is kept afterwards so no write is interrupted
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shuttingDown.Store(true)
+	err := s.server.Shutdown(ctx)
+	for _, server := range s.dataServers() {
+		if server.inbox != nil {
+			server.inbox.Stop()
+		}
+		server.season.Lock()
+	}
+	s.logger.Info("server stopped")
+	return err
+}
+
+// handler routes of the server with the proxy, access log and security middlewares
+func (s *Server) handler() http.Handler {
+	mux := s.routes()
+	return s.proxy(s.accessLog(mux, s.secure(mux)))
+}
+
+func (s *Server) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	mux.HandleFunc("GET /readyz", s.handleReady)
+	mux.HandleFunc("GET /metrics", s.require(RoleRead, s.handleMetrics))
+	s.staticRoute(mux)
+
+	if len(s.leagues) > 0 {
+		s.leagueRoutes(mux)
+	} else {
+		s.dataRoutes(mux)
+	}
+	return mux
+}
+
+// staticRoute static assets of the theme below /public/
+func (s *Server) staticRoute(mux *http.ServeMux) {
+	mux.Handle("/public/", http.StripPrefix("/public", http.FileServer(http.FS(s.static))))
+}
+
+// dataRoutes pages and api of the race data, served at the root
+// or below /{league}/ for every league
+func (s *Server) dataRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/", s.require(RoleRead, s.readLocked(s.handleIndex)))
+	mux.HandleFunc("/login", s.form(s.maxUploadSize, s.handleLogin))
+	mux.HandleFunc("/logout", s.form(s.maxUploadSize, s.handleLogout))
+	mux.HandleFunc("/show/{season}/{race}", s.require(RoleRead, s.readLocked(s.handleShowRace)))
+	mux.HandleFunc("/delete/{season}/{race}", s.require(RoleAdmin, s.form(s.maxUploadSize, s.locked(s.handleDeleteRace))))
+	mux.HandleFunc("/addPenalty/{season}/{race}", s.require(RoleSteward, s.form(s.maxUploadSize, s.locked(s.handleAddPenalty))))
+	mux.HandleFunc("/fetch/{season}/{race}", s.require(RoleSteward, s.form(s.maxUploadSize, s.handleFetchResults)))
+	mux.HandleFunc("/newSeason", s.require(RoleAdmin, s.form(s.maxUploadSize, s.locked(s.handleNewSeason))))
+	mux.HandleFunc("/importSeason", s.require(RoleAdmin, s.form(s.maxArchiveSize, s.locked(s.handleImportSeason))))
+	mux.HandleFunc("/upload/{season}", s.require(RoleSteward, s.form(s.maxUploadSize, s.locked(s.handleUpload))))
+	mux.HandleFunc("/laps/{season}/{race}", s.require(RoleSteward, s.form(s.maxUploadSize, s.locked(s.handleUploadLaps))))
+	mux.HandleFunc("GET /analysis/{season}/{race}", s.require(RoleRead, s.readLocked(s.handleShowAnalysis)))
+	mux.HandleFunc("/export/csv/{season}/{race}/{split}", s.require(RoleRead, s.readLocked(s.handleExportRace)))
+	mux.HandleFunc("/export/qualy/{season}/{race}/{split}", s.require(RoleRead, s.readLocked(s.handleExportQualy)))
+	mux.HandleFunc("/uploadEntryList/{season}", s.require(RoleAdmin, s.form(s.maxUploadSize, s.locked(s.handleUploadEntyList))))
+	mux.HandleFunc("GET /season/{season}/entrylist", s.require(RoleRead, s.readLocked(s.handleShowEntyList)))
+	mux.HandleFunc("GET /driver/{id}", s.require(RoleRead, s.readLocked(s.handleShowDriver)))
+	mux.HandleFunc("GET /drivers", s.require(RoleRead, s.readLocked(s.handleDrivers)))
+	mux.HandleFunc("POST /drivers/merge", s.require(RoleAdmin, s.form(s.maxUploadSize, s.locked(s.handleMergeDrivers))))
+	mux.HandleFunc("POST /drivers/{id}", s.require(RoleAdmin, s.form(s.maxUploadSize, s.locked(s.handleUpdateDriver))))
+	mux.HandleFunc("GET /team/{id}", s.require(RoleRead, s.readLocked(s.handleShowTeam)))
+	mux.HandleFunc("/branding", s.require(RoleAdmin, s.form(s.maxUploadSize, s.locked(s.handleBranding))))
+	mux.HandleFunc("/branding/{season}", s.require(RoleAdmin, s.form(s.maxUploadSize, s.locked(s.handleBranding))))
+	mux.HandleFunc("GET /logo", s.readLocked(s.handleLogo))
+	mux.HandleFunc("GET /logo/{season}", s.readLocked(s.handleLogo))
+	mux.HandleFunc("/api/hooks/results/{season}/{race}", s.handleResultHook)
+	s.registerAPI(mux)
+}
+
+// locked run handlers modifying race data one at a time,
+// also serialized with the inbox imports
+func (s *Server) locked(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.season.Lock()
+		defer s.season.Unlock()
+		s.resolveNames(r)
+		h(w, r)
+	}
+}
+
+// readLocked run handlers reading race data
+func (s *Server) readLocked(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.season.RLock()
+		defer s.season.RUnlock()
+		s.resolveNames(r)
+		h(w, r)
+	}
+}
+
+// resolveNames replace season and race slugs in the path with their names,
+// unknown values are kept so handlers report them as not found
+func (s *Server) resolveNames(r *http.Request) {
+	seasonName, found := s.season.SeasonName(r.PathValue("season"))
+	if !found {
+		return
+	}
+	r.SetPathValue("season", seasonName)
+	if raceName, found := s.season.RaceName(seasonName, r.PathValue("race")); found {
+		r.SetPathValue("race", raceName)
+	}
+}
+
 func (s *Server) handleShowEntyList(w http.ResponseWriter, r *http.Request) {
-	log.Printf("-> handleShowEntyList entylist %v\n", strings.ReplaceAll(r.RequestURI, "/showEntryList/", ""))
-	defer logDuration(r.RequestURI, time.Now())
+	requestLogger(r).Debug("show entry list", "season", r.PathValue("season"))
 
-	entryList, err := s.season.GetEntryList(strings.ReplaceAll(r.RequestURI, "/showEntryList/", ""))
+	entryList, err := s.season.GetSeasonEntryList(r.PathValue("season"))
+	if errors.Is(err, racedata.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if err := entrylistTmpl.ExecuteTemplate(w, "entrylist.html", entryList); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	data := struct {
+		Base      string
+		Brand     brand
+		EntryList *racedata.EntryList
+	}{
+		Base:      base(r),
+		Brand:     s.brand(r, r.PathValue("season")),
+		EntryList: entryList,
 	}
+
+	s.render(w, r, "entrylist.html", data)
 }
 
 func (s *Server) handleExportRace(w http.ResponseWriter, r *http.Request) {
-	log.Printf("-> handleExportRace season %v, race %v, split %v\n", r.PathValue("season"), r.PathValue("race"), r.PathValue("split"))
-	defer logDuration(r.RequestURI, time.Now())
+	s.exportCSV(w, r, "", racedata.GetCSVExport)
+}
+
+func (s *Server) handleExportQualy(w http.ResponseWriter, r *http.Request) {
+	s.exportCSV(w, r, "qualy", racedata.GetQualyCSVExport)
+}
+
+// exportCSV csv download of a split, named after league, season, race,
+// split and the kind of the export
+func (s *Server) exportCSV(w http.ResponseWriter, r *http.Request, kind string, export func(*racedata.RaceResult, string, io.Writer)) {
+	requestLogger(r).Debug("export race", "season", r.PathValue("season"), "race", r.PathValue("race"), "split", r.PathValue("split"), "kind", kind)
 
 	seasonName := r.PathValue("season")
 	raceName := r.PathValue("race")
@@ -85,71 +282,268 @@ func (s *Server) handleExportRace(w http.ResponseWriter, r *http.Request) {
 
 	raceResult, err := s.season.GetRaceResult(seasonName, raceName)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
-	racedata.GetCSVExport(raceResult, splitName, w)
+	_, raceSplit := raceResult.RaceResultWithPenalty[splitName]
+	_, qualySplit := raceResult.QualiyResult[splitName]
+	if !raceSplit && !qualySplit {
+		http.Error(w, fmt.Sprintf("split %v %v", splitName, racedata.ErrNotFound), http.StatusNotFound)
+		return
+	}
+
+	parts := []string{
+		racedata.Slugify(s.season.GetBranding(seasonName).Name),
+		raceResult.SeasonSlug, raceResult.RaceSlug, racedata.Slugify(splitName),
+	}
+	if kind != "" {
+		parts = append(parts, kind)
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", strings.Join(parts, "-")+".csv"))
+	export(raceResult, splitName, w)
 }
 
 func (s *Server) handleAddPenalty(w http.ResponseWriter, r *http.Request) {
 	pos := r.PostFormValue("pos")
 	penalty := r.PostFormValue("penalty")
+	session := r.PostFormValue("session")
 	seasonName := r.PathValue("season")
 	raceName := r.PathValue("race")
-	log.Printf("handleAddPenalty season: %v race: %v, add penalty %v to pos %v\n", seasonName, raceName, penalty, pos)
-	s.season.AddPenalty(seasonName, raceName, penalty, pos)
+	requestLogger(r).Info("add penalty", "season", seasonName, "race", raceName, "session", session, "pos", pos, "penalty", penalty)
+	if err := s.season.AddPenalty(seasonName, raceName, session, penalty, pos); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
 	s.handleShowRace(w, r)
 }
 
+// handleFetchResults download the race results, race data is locked by the
+// fetcher only while adding the results so a slow endpoint does not block the server
+func (s *Server) handleFetchResults(w http.ResponseWriter, r *http.Request) {
+	s.season.RLock()
+	s.resolveNames(r)
+	seasonName := r.PathValue("season")
+	raceName := r.PathValue("race")
+	_, _, err := s.season.GetResultFilenames(seasonName, raceName)
+	s.season.RUnlock()
+
+	eventID := r.PostFormValue("event_id")
+	requestLogger(r).Info("fetch results", "season", seasonName, "race", raceName, "event", eventID)
+
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.fetcher == nil {
+		http.Error(w, "no fetch url configured", http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	if err := s.fetcher.FetchResults(s.season, seasonName, raceName, eventID); errors.Is(err, racedata.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	s.readLocked(s.handleShowRace)(w, r)
+}
+
 func (s *Server) handleDeleteRace(w http.ResponseWriter, r *http.Request) {
 	seasonName := r.PathValue("season")
 	raceName := r.PathValue("race")
-	log.Printf("handleDeleteRace season %v race %v\n", seasonName, raceName)
-	s.season.RemoveRace(seasonName, raceName)
+	requestLogger(r).Info("delete race", "season", seasonName, "race", raceName)
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.season.RemoveRace(seasonName, raceName); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
 	s.handleIndex(w, r)
 }
 
 func (s *Server) handleShowRace(w http.ResponseWriter, r *http.Request) {
-	log.Printf("-> handleShowRace race %v, season %v\n", r.PathValue("season"), r.PathValue("name"))
-	defer logDuration(r.RequestURI, time.Now())
-
 	seasonName := r.PathValue("season")
 	raceName := r.PathValue("race")
+	requestLogger(r).Debug("show race", "season", seasonName, "race", raceName)
 
 	raceResult, err := s.season.GetRaceResult(seasonName, raceName)
+	if err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	data := struct {
+		*racedata.RaceResult
+		Base       string
+		Brand      brand
+		CanFetch   bool
+		HasLapData bool
+		Access     access
+	}{
+		RaceResult: raceResult,
+		Base:       base(r),
+		Brand:      s.brand(r, seasonName),
+		CanFetch:   s.fetcher != nil && role(r) >= RoleSteward,
+		HasLapData: s.season.HasLapData(seasonName, raceName),
+		Access:     s.access(r),
+	}
+
+	s.render(w, r, "race.html", data)
+
+}
+
+// handleUploadLaps add the lap by lap csv to the race and show the analysis
+func (s *Server) handleUploadLaps(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	seasonName := r.PathValue("season")
+	raceName := r.PathValue("race")
+	requestLogger(r).Info("upload lap data", "season", seasonName, "race", raceName)
+
+	lapsFile, _, err := r.FormFile("lap_data")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer lapsFile.Close()
+
+	laps, err := io.ReadAll(lapsFile)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	if err := raceTmpl.ExecuteTemplate(w, "race.html", raceResult); err != nil {
+	if err := s.season.AddLapData(seasonName, raceName, laps); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, racedata.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	s.handleShowAnalysis(w, r)
+}
+
+// handleShowAnalysis position and lap time charts of every split
+func (s *Server) handleShowAnalysis(w http.ResponseWriter, r *http.Request) {
+	seasonName := r.PathValue("season")
+	raceName := r.PathValue("race")
+	requestLogger(r).Debug("show analysis", "season", seasonName, "race", raceName)
+
+	analysis, err := s.season.GetRaceAnalysis(seasonName, raceName)
+	if errors.Is(err, racedata.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	data := struct {
+		*racedata.RaceAnalysis
+		Base  string
+		Brand brand
+	}{
+		RaceAnalysis: analysis,
+		Base:         base(r),
+		Brand:        s.brand(r, seasonName),
+	}
+
+	s.render(w, r, "analysis.html", data)
 }
 
 func (s *Server) handleNewSeason(w http.ResponseWriter, r *http.Request) {
-	log.Printf("handleNewSeason\n")
 	if r.Method != "POST" {
 		http.Error(w, "", http.StatusMethodNotAllowed)
 		return
 	}
 	newSeasonName := r.PostFormValue("new_season_name")
-	log.Printf("new season: %v\n", newSeasonName)
+	requestLogger(r).Info("new season", "season", newSeasonName)
 	if err := s.season.AddSeason(newSeasonName); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 	s.handleIndex(w, r)
 }
 
+func (s *Server) handleImportSeason(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "", http.StatusMethodNotAllowed)
+		return
+	}
+
+	seasonName := r.PostFormValue("season_name")
+	requestLogger(r).Info("import season", "season", seasonName)
+
+	archiveFile, _, err := r.FormFile("season_archive")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	defer archiveFile.Close()
+
+	archive, err := io.ReadAll(archiveFile)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report, err := s.season.ImportSeasonArchive(seasonName, archive)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	data := struct {
+		*racedata.ImportReport
+		Base  string
+		Brand brand
+	}{
+		ImportReport: report,
+		Base:         base(r),
+		Brand:        s.brand(r, seasonName),
+	}
+
+	s.render(w, r, "importreport.html", data)
+}
+
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	log.Printf("-> handleIndex\n")
-	defer logDuration(r.RequestURI, time.Now())
-	if err := indexTmpl.ExecuteTemplate(w, "index.html", s.season.Seasons); err != nil {
-		log.Fatal(err)
+	data := struct {
+		Base          string
+		Brand         brand
+		Seasons       racedata.SeasonMap
+		ResultFormats []string
+		CSVProfiles   []string
+		InboxEvents   []racedata.InboxEvent
+		Access        access
+	}{
+		Base:          base(r),
+		Brand:         s.brand(r, ""),
+		Seasons:       s.season.Seasons,
+		ResultFormats: racedata.ImporterNames(),
+		CSVProfiles:   racedata.CSVProfileNames(),
+		Access:        s.access(r),
+	}
+	if s.inbox != nil {
+		data.InboxEvents = s.inbox.Events()
 	}
+	s.render(w, r, "index.html", data)
 }
 
 func (s *Server) handleUploadEntyList(w http.ResponseWriter, r *http.Request) {
@@ -159,14 +553,7 @@ func (s *Server) handleUploadEntyList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	seasonName := r.PathValue("season")
-	log.Printf("handleUploadEntyList for seasons %v\n", seasonName)
-
-	r.Body = http.MaxBytesReader(w, r.Body, MAX_UPLOAD_SIZE)
-
-	if err := r.ParseMultipartForm(MAX_UPLOAD_SIZE); err != nil {
-		http.Error(w, "The uploaded file is too big. Please choose an file that's less than 1MB in size", http.StatusBadRequest)
-		return
-	}
+	requestLogger(r).Info("upload entry list", "season", seasonName)
 
 	entryListFile, _, err := r.FormFile("entry_list")
 	if err != nil {
@@ -182,8 +569,10 @@ func (s *Server) handleUploadEntyList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.season.AddEntryList(seasonName, entryList); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	profileName := r.PostFormValue("csv_profile")
+
+	if err := s.season.AddEntryList(seasonName, profileName, entryList); err != nil {
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 	s.handleIndex(w, r)
@@ -191,8 +580,6 @@ func (s *Server) handleUploadEntyList(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
-	log.Printf("handleUpload\n")
-
 	if r.Method != "POST" {
 		http.Error(w, "", http.StatusMethodNotAllowed)
 		return
@@ -200,16 +587,10 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 
 	newRaceName := r.PostFormValue("new_race_name")
 	seasonName := r.PathValue("season")
+	requestLogger(r).Info("upload results", "season", seasonName, "race", newRaceName, "format", r.PostFormValue("result_format"))
 
 	if err := s.season.AddRace(seasonName, newRaceName); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	r.Body = http.MaxBytesReader(w, r.Body, MAX_UPLOAD_SIZE)
-
-	if err := r.ParseMultipartForm(MAX_UPLOAD_SIZE); err != nil {
-		http.Error(w, "The uploaded file is too big. Please choose an file that's less than 1MB in size", http.StatusBadRequest)
+		http.Error(w, err.Error(), errorStatus(err))
 		return
 	}
 
@@ -245,7 +626,9 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.season.AddResults(seasonName, newRaceName, qualyResult, raceResult); err != nil {
+	resultFormat := r.PostFormValue("result_format")
+
+	if err := s.season.ImportResults(seasonName, newRaceName, resultFormat, qualyResult, raceResult); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -254,7 +637,23 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func logDuration(s string, t time.Time) {
-	d := time.Now().Sub(t)
-	log.Printf("<- %v time: %v\n", s, d)
+// errorStatus http status for errors of race data operations in pages
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, racedata.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, racedata.ErrNotUnique):
+		return http.StatusConflict
+	case errors.Is(err, racedata.ErrInvalidName), errors.Is(err, racedata.ErrInvalidValue):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// formatSize human readable upload size
+func formatSize(size int64) string {
+	if size%(1024*1024) == 0 {
+		return fmt.Sprintf("%vMB", size/(1024*1024))
+	}
+	return fmt.Sprintf("%vKB", size/1024)
 }
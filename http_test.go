@@ -0,0 +1,35 @@
+package sgphelper
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestAddPenaltyForm(t *testing.T) {
+	ts := newTestServer(t)
+
+	for _, tc := range []struct {
+		fields url.Values
+		status int
+	}{
+		{url.Values{"pos": {"1"}, "penalty": {"-30"}}, http.StatusBadRequest},
+		{url.Values{"pos": {"1"}, "penalty": {"ten"}}, http.StatusBadRequest},
+		{url.Values{"pos": {"1"}, "penalty": {"5"}, "session": {"warmup"}}, http.StatusBadRequest},
+		{url.Values{"pos": {"7"}, "penalty": {"5"}}, http.StatusNotFound},
+		{url.Values{"pos": {"1"}, "penalty": {"5"}, "session": {"race"}}, http.StatusOK},
+	} {
+		w := ts.serve(formRequest("/addPenalty/season-1/race-1", tc.fields))
+		if w.Code != tc.status {
+			t.Errorf("%v: status %v, want %v: %v", tc.fields, w.Code, tc.status, w.Body.String())
+		}
+	}
+
+	raceResult, err := ts.data.GetRaceResult("Season 1", "Race 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if penalty := raceResult.RaceResultWithPenalty["GT3"][0].Penalty; penalty != "5" {
+		t.Errorf("penalty %v, want only the valid 5s", penalty)
+	}
+}
@@ -0,0 +1,168 @@
+package sgphelper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"sgpHelper/racedata"
+)
+
+// reservedLeagueSlugs root routes which can not be used as league urls
+var reservedLeagueSlugs = []string{"public", "healthz", "readyz", "metrics"}
+
+// league hosted below /{slug}/ by its own server with its own race data,
+// branding and admins, so handlers only ever see the data of one league
+type league struct {
+	name   string
+	slug   string
+	server *Server
+	mux    *http.ServeMux
+}
+
+// AddLeague host the league below /{slug}/ with its own race data, the slug
+// is derived from the name if empty. Auth, inbox, fetcher and hook token
+// are set on the returned server of the league
+func (s *Server) AddLeague(name string, slug string, raceData *racedata.RaceData) (*Server, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("league name %q %w", name, racedata.ErrInvalidName)
+	}
+	if slug == "" {
+		slug = racedata.Slugify(name)
+	}
+	if slug == "" || slug != racedata.Slugify(slug) {
+		return nil, fmt.Errorf("league slug %q %w, use a-z, 0-9 and -", slug, racedata.ErrInvalidName)
+	}
+	for _, reserved := range reservedLeagueSlugs {
+		if slug == reserved {
+			return nil, fmt.Errorf("league slug %q is reserved", slug)
+		}
+	}
+	if s.findLeague(slug) != nil {
+		return nil, fmt.Errorf("league slug %v %w", slug, racedata.ErrNotUnique)
+	}
+
+	server := &Server{
+		season:  raceData,
+		logger:  s.logger.With("league", slug),
+		metrics: s.metrics,
+	}
+	s.leagues = append(s.leagues, &league{name: name, slug: slug, server: server})
+	sort.Slice(s.leagues, func(i, j int) bool { return s.leagues[i].name < s.leagues[j].name })
+	return server, nil
+}
+
+func (s *Server) findLeague(slug string) *league {
+	for _, l := range s.leagues {
+		if l.slug == slug {
+			return l
+		}
+	}
+	return nil
+}
+
+// dataServers servers with race data, the leagues or the server itself
+func (s *Server) dataServers() []*Server {
+	if len(s.leagues) == 0 {
+		return []*Server{s}
+	}
+	servers := make([]*Server, len(s.leagues))
+	for i, l := range s.leagues {
+		servers[i] = l.server
+	}
+	return servers
+}
+
+// leagueRoutes league list at the root and the race data routes of every
+// league below /{league}/, templates, static assets and limits are taken
+// from the server. The pages of a league link the static assets below its
+// base path
+func (s *Server) leagueRoutes(mux *http.ServeMux) {
+	for _, l := range s.leagues {
+		l.server.logger = s.logger.With("league", l.slug)
+		l.server.templates = s.templates
+		l.server.static = s.static
+		l.server.maxUploadSize = s.maxUploadSize
+		l.server.maxArchiveSize = s.maxArchiveSize
+		l.mux = http.NewServeMux()
+		l.server.staticRoute(l.mux)
+		l.server.dataRoutes(l.mux)
+	}
+
+	mux.HandleFunc("GET /{$}", s.handleLeagues)
+	mux.HandleFunc("GET /{league}", func(w http.ResponseWriter, r *http.Request) {
+		if s.findLeague(r.PathValue("league")) == nil {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, base(r)+"/"+r.PathValue("league")+"/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/{league}/", s.handleLeague)
+}
+
+// leagueRequest league of the request and the request with the league
+// prefix moved from the path to the base path
+func (s *Server) leagueRequest(r *http.Request) (*league, *http.Request) {
+	slug, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	l := s.findLeague(slug)
+	if l == nil {
+		return nil, r
+	}
+	prefix := "/" + l.slug
+	ctx := context.WithValue(r.Context(), basePathKey{}, base(r)+prefix)
+	ctx = context.WithValue(ctx, loggerKey{}, requestLogger(r).With("league", l.slug))
+	lr := r.Clone(ctx)
+	lr.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+	lr.URL.RawPath = ""
+	return l, lr
+}
+
+// handleLeague serve the request with the routes of the league
+func (s *Server) handleLeague(w http.ResponseWriter, r *http.Request) {
+	l, lr := s.leagueRequest(r)
+	if l == nil {
+		http.NotFound(w, r)
+		return
+	}
+	l.mux.ServeHTTP(w, lr)
+}
+
+// route pattern of the request for logs and metrics, requests of a
+// league report the league route, e.g. GET /{league}/season/{season}/entrylist
+func (s *Server) route(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if pattern != "/{league}/" {
+		return pattern
+	}
+	l, lr := s.leagueRequest(r)
+	if l == nil {
+		return pattern
+	}
+	_, pattern = l.mux.Handler(lr)
+	if method, path, found := strings.Cut(pattern, " "); found {
+		return method + " /{league}" + path
+	}
+	return "/{league}" + pattern
+}
+
+// handleLeagues list of the hosted leagues
+func (s *Server) handleLeagues(w http.ResponseWriter, r *http.Request) {
+	type leagueLink struct {
+		Name string
+		Slug string
+	}
+	data := struct {
+		Base    string
+		Brand   brand
+		Leagues []leagueLink
+	}{
+		Base:  base(r),
+		Brand: s.brand(r, ""),
+	}
+	for _, l := range s.leagues {
+		data.Leagues = append(data.Leagues, leagueLink{Name: l.name, Slug: l.slug})
+	}
+	s.render(w, r, "leagues.html", data)
+}
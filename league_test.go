@@ -0,0 +1,85 @@
+package sgphelper
+
+import (
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sgpHelper/racedata"
+)
+
+// newLeagueServer server hosting the leagues alpha and beta, each with
+// its own season, and a theme overriding style.css
+func newLeagueServer(t *testing.T) *testServer {
+	t.Helper()
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	s := NewServer(":0", nil)
+	s.SetLogger(logger)
+	theme := filepath.Join(dir, "theme")
+	if err := os.MkdirAll(filepath.Join(theme, "public"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(theme, "public", "style.css"), "/* theme */")
+	if err := s.SetTheme(theme); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, slug := range []string{"alpha", "beta"} {
+		dataDir := filepath.Join(dir, slug)
+		if err := os.MkdirAll(dataDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		rd := racedata.NewRaceData(dataDir, filepath.Join(dataDir, "race_data.json"), logger)
+		if err := rd.AddSeason("Season " + slug); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.AddLeague(strings.ToUpper(slug), slug, rd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &testServer{Server: s, dir: dir}
+}
+
+func TestLeagueRoutes(t *testing.T) {
+	ts := newLeagueServer(t)
+
+	for _, tc := range []struct {
+		path        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"/", 200, "text/html", "/alpha/"},
+		{"/alpha", 301, "", ""},
+		{"/alpha/", 200, "text/html", "Season alpha"},
+		{"/beta/", 200, "text/html", "Season beta"},
+		{"/gamma/", 404, "", ""},
+		{"/public/style.css", 200, "text/css", "/* theme */"},
+		{"/alpha/public/style.css", 200, "text/css", "/* theme */"},
+		{"/beta/public/favicon.ico", 200, "image/", ""},
+		{"/alpha/public/missing.css", 404, "", ""},
+	} {
+		w := ts.serve(httptest.NewRequest("GET", tc.path, nil))
+		if w.Code != tc.status {
+			t.Errorf("%v status %v, want %v", tc.path, w.Code, tc.status)
+			continue
+		}
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), tc.contentType) {
+			t.Errorf("%v content type %q, want %q", tc.path, w.Header().Get("Content-Type"), tc.contentType)
+		}
+		if !strings.Contains(w.Body.String(), tc.body) {
+			t.Errorf("%v body does not contain %q", tc.path, tc.body)
+		}
+	}
+
+	// the season of one league is not visible in the other
+	if w := ts.serve(httptest.NewRequest("GET", "/alpha/", nil)); strings.Contains(w.Body.String(), "Season beta") {
+		t.Error("season of league beta shown in league alpha")
+	}
+}
@@ -0,0 +1,110 @@
+package sgphelper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// REQUEST_ID_HEADER header with the id of the request, taken from
+// the client or a proxy if valid, otherwise generated
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+type loggerKey struct{}
+
+// statusWriter response writer remembering status and size for the access log
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// SetLogger logger of the server, the default logger if not set
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// accessLog assign a request id, add a logger with the id to the request
+// context and log method, route, status, size and latency of every request
+func (s *Server) accessLog(mux *http.ServeMux, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		id := r.Header.Get(REQUEST_ID_HEADER)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(REQUEST_ID_HEADER, id)
+
+		logger := s.logger.With("request_id", id)
+		sw := &statusWriter{ResponseWriter: w}
+		route := s.route(mux, r)
+
+		h.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), loggerKey{}, logger)))
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		s.metrics.observe(r.Method, route, sw.status, time.Since(start))
+		logger.Info("request",
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"status", sw.status,
+			"size", sw.size,
+			"duration", time.Since(start),
+			"remote", r.RemoteAddr,
+		)
+	})
+}
+
+// requestLogger logger of the request with its request id
+func requestLogger(r *http.Request) *slog.Logger {
+	if logger, ok := r.Context().Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
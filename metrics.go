@@ -0,0 +1,185 @@
+package sgphelper
+
+import (
+	"fmt"
+	"net/http"
+	"sgpHelper/racedata"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// durationBuckets upper bounds in seconds of the request latency histogram
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type requestKey struct {
+	method string
+	route  string
+	status int
+}
+
+type histogram struct {
+	buckets []uint64
+	count   uint64
+	sum     float64
+}
+
+// metrics request counts and latencies per route in prometheus text format
+type metrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[string]*histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:  map[requestKey]uint64{},
+		durations: map[string]*histogram{},
+	}
+}
+
+// observe count the request and add its latency to the histogram of the route
+func (m *metrics) observe(method string, route string, status int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{method: method, route: route, status: status}]++
+
+	h, found := m.durations[route]
+	if !found {
+		h = &histogram{buckets: make([]uint64, len(durationBuckets))}
+		m.durations[route] = h
+	}
+	seconds := d.Seconds()
+	for i, bound := range durationBuckets {
+		if seconds <= bound {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+func (m *metrics) write(b *strings.Builder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	b.WriteString("# HELP sgphelper_http_requests_total Number of http requests.\n")
+	b.WriteString("# TYPE sgphelper_http_requests_total counter\n")
+	for _, k := range keys {
+		fmt.Fprintf(b, "sgphelper_http_requests_total{method=%q,route=%q,status=\"%d\"} %d\n",
+			k.method, k.route, k.status, m.requests[k])
+	}
+
+	routes := make([]string, 0, len(m.durations))
+	for route := range m.durations {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	b.WriteString("# HELP sgphelper_http_request_duration_seconds Latency of http requests.\n")
+	b.WriteString("# TYPE sgphelper_http_request_duration_seconds histogram\n")
+	for _, route := range routes {
+		h := m.durations[route]
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(b, "sgphelper_http_request_duration_seconds_bucket{route=%q,le=\"%v\"} %d\n", route, bound, h.buckets[i])
+		}
+		fmt.Fprintf(b, "sgphelper_http_request_duration_seconds_bucket{route=%q,le=\"+Inf\"} %d\n", route, h.count)
+		fmt.Fprintf(b, "sgphelper_http_request_duration_seconds_sum{route=%q} %v\n", route, h.sum)
+		fmt.Fprintf(b, "sgphelper_http_request_duration_seconds_count{route=%q} %d\n", route, h.count)
+	}
+}
+
+// writeGauge gauge with a value per league, without leagues the
+// value has no label
+func writeGauge(b *strings.Builder, name string, help string, values map[string]int) {
+	fmt.Fprintf(b, "# HELP %v %v\n# TYPE %v gauge\n", name, help, name)
+	leagues := make([]string, 0, len(values))
+	for league := range values {
+		leagues = append(leagues, league)
+	}
+	sort.Strings(leagues)
+	for _, league := range leagues {
+		if league == "" {
+			fmt.Fprintf(b, "%v %d\n", name, values[league])
+		} else {
+			fmt.Fprintf(b, "%v{league=%q} %d\n", name, league, values[league])
+		}
+	}
+}
+
+// handleMetrics request metrics and race data counts in prometheus text format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	s.metrics.write(&b)
+
+	seasons, races, results, penalties := map[string]int{}, map[string]int{}, map[string]int{}, map[string]int{}
+	for league, stats := range s.stats() {
+		seasons[league] = stats.Seasons
+		races[league] = stats.Races
+		results[league] = stats.Results
+		penalties[league] = stats.Penalties
+	}
+	writeGauge(&b, "sgphelper_seasons", "Number of seasons.", seasons)
+	writeGauge(&b, "sgphelper_races", "Number of races.", races)
+	writeGauge(&b, "sgphelper_race_results", "Number of races with results.", results)
+	writeGauge(&b, "sgphelper_penalties", "Number of race result lines with a penalty.", penalties)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// stats race data counts by league slug, without leagues
+// the counts of the server are returned for an empty slug
+func (s *Server) stats() map[string]racedata.Stats {
+	if len(s.leagues) == 0 {
+		s.season.RLock()
+		defer s.season.RUnlock()
+		return map[string]racedata.Stats{"": s.season.GetStats()}
+	}
+
+	stats := map[string]racedata.Stats{}
+	for _, l := range s.leagues {
+		l.server.season.RLock()
+		stats[l.slug] = l.server.season.GetStats()
+		l.server.season.RUnlock()
+	}
+	return stats
+}
+
+// handleHealth liveness, ok as long as the server handles requests
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// handleReady readiness, fails while the server shuts down or
+// if a data directory is not writable
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if s.shuttingDown.Load() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	for _, server := range s.dataServers() {
+		if err := server.season.CheckWritable(); err != nil {
+			requestLogger(r).Error("data directory is not writable", "error", err)
+			http.Error(w, "data directory is not writable", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,32 @@
+package sgphelper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestHealthAndReady(t *testing.T) {
+	ts := newTestServer(t)
+	for path, want := range map[string]int{"/healthz": http.StatusOK, "/readyz": http.StatusOK} {
+		if w := ts.serve(httptest.NewRequest("GET", path, nil)); w.Code != want {
+			t.Errorf("%v status %v, want %v", path, w.Code, want)
+		}
+	}
+
+	// a file in place of the data directory makes it unwritable
+	dataDir := filepath.Join(ts.dir, "not-a-dir")
+	writeFile(t, dataDir, "")
+	ts.data.DataDir = dataDir
+	for path, want := range map[string]int{"/healthz": http.StatusOK, "/readyz": http.StatusServiceUnavailable} {
+		if w := ts.serve(httptest.NewRequest("GET", path, nil)); w.Code != want {
+			t.Errorf("unwritable data directory, %v status %v, want %v", path, w.Code, want)
+		}
+	}
+
+	ts.shuttingDown.Store(true)
+	if w := ts.serve(httptest.NewRequest("GET", "/readyz", nil)); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz status %v while shutting down", w.Code)
+	}
+}
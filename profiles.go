@@ -0,0 +1,126 @@
+package sgphelper
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"sgpHelper/racedata"
+)
+
+// handleShowDriver career of the driver over all seasons, merged
+// duplicates redirect to the driver they were merged into
+func (s *Server) handleShowDriver(w http.ResponseWriter, r *http.Request) {
+	requestLogger(r).Debug("show driver", "driver", r.PathValue("id"))
+
+	profile, err := s.season.GetDriverProfile(r.PathValue("id"))
+	if errors.Is(err, racedata.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if profile.ID != r.PathValue("id") {
+		http.Redirect(w, r, base(r)+"/driver/"+profile.ID, http.StatusMovedPermanently)
+		return
+	}
+
+	data := struct {
+		*racedata.DriverProfile
+		Base  string
+		Brand brand
+	}{
+		DriverProfile: profile,
+		Base:          base(r),
+		Brand:         s.brand(r, ""),
+	}
+
+	s.render(w, r, "driver.html", data)
+}
+
+// handleShowTeam cars, drivers, championship positions and results of the
+// team over all seasons
+func (s *Server) handleShowTeam(w http.ResponseWriter, r *http.Request) {
+	requestLogger(r).Debug("show team", "team", r.PathValue("id"))
+
+	profile, err := s.season.GetTeamProfile(r.PathValue("id"))
+	if errors.Is(err, racedata.ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		*racedata.TeamProfile
+		Base  string
+		Brand brand
+	}{
+		TeamProfile: profile,
+		Base:        base(r),
+		Brand:       s.brand(r, ""),
+	}
+
+	s.render(w, r, "team.html", data)
+}
+
+// handleDrivers driver registry with forms to edit and merge drivers
+func (s *Server) handleDrivers(w http.ResponseWriter, r *http.Request) {
+	s.renderDrivers(w, r, nil)
+}
+
+// handleUpdateDriver save name, aliases, simracing.gp user id and
+// nationality of the driver, aliases are separated by commas
+func (s *Server) handleUpdateDriver(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	requestLogger(r).Info("update driver", "driver", id)
+
+	err := s.season.UpdateDriver(id, racedata.RegisteredDriver{
+		Name:        r.PostFormValue("name"),
+		Aliases:     strings.Split(r.PostFormValue("aliases"), ","),
+		SGPUserID:   r.PostFormValue("sgp_user_id"),
+		Nationality: r.PostFormValue("nationality"),
+	})
+	s.renderDrivers(w, r, err)
+}
+
+// handleMergeDrivers merge the duplicate driver into the other driver
+func (s *Server) handleMergeDrivers(w http.ResponseWriter, r *http.Request) {
+	duplicate := r.PostFormValue("duplicate")
+	into := r.PostFormValue("into")
+	requestLogger(r).Info("merge drivers", "duplicate", duplicate, "into", into)
+
+	s.renderDrivers(w, r, s.season.MergeDrivers(duplicate, into))
+}
+
+// renderDrivers driver registry page, with the error of a failed change
+func (s *Server) renderDrivers(w http.ResponseWriter, r *http.Request, err error) {
+	data := struct {
+		Base    string
+		Brand   brand
+		Drivers []racedata.RegisteredDriver
+		Error   string
+		Saved   bool
+		Access  access
+	}{
+		Base:    base(r),
+		Brand:   s.brand(r, ""),
+		Drivers: s.season.GetDrivers(),
+		Saved:   r.Method == "POST" && err == nil,
+		Access:  s.access(r),
+	}
+	if err != nil {
+		requestLogger(r).Warn("invalid driver change", "error", err)
+		data.Error = err.Error()
+		if errors.Is(err, racedata.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}
+	s.render(w, r, "drivers.html", data)
+}
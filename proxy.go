@@ -0,0 +1,107 @@
+package sgphelper
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type basePathKey struct{}
+
+// SetBasePath serve the application below the path, e.g. /results
+// behind a reverse proxy, trusted proxies may send X-Forwarded-Prefix instead
+func (s *Server) SetBasePath(basePath string) {
+	s.basePath = cleanBasePath(basePath)
+}
+
+// SetTrustProxy honour the X-Forwarded-For, -Proto, -Host and -Prefix headers,
+// only enable it if the server is reachable through the proxy alone
+func (s *Server) SetTrustProxy(trust bool) {
+	s.trustProxy = trust
+}
+
+// SetTLS serve https with the certificate and key files
+func (s *Server) SetTLS(certFile string, keyFile string) {
+	s.tlsCert = certFile
+	s.tlsKey = keyFile
+}
+
+// proxy apply forwarded headers of trusted proxies and strip the base path,
+// requests without the base path were already stripped by the proxy
+func (s *Server) proxy(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		basePath := s.basePath
+		if s.trustProxy {
+			r = r.Clone(r.Context())
+			if prefix := r.Header.Get("X-Forwarded-Prefix"); prefix != "" {
+				basePath = cleanBasePath(prefix)
+			}
+			if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+				r.URL.Scheme = strings.ToLower(strings.TrimSpace(strings.Split(proto, ",")[0]))
+			}
+			if host := r.Header.Get("X-Forwarded-Host"); host != "" {
+				r.Host = strings.TrimSpace(strings.Split(host, ",")[0])
+			}
+			if ip := forwardedFor(r); ip != "" {
+				r.RemoteAddr = ip
+			}
+		}
+
+		if basePath != "" {
+			if r.URL.Path == basePath {
+				http.Redirect(w, r, basePath+"/", http.StatusMovedPermanently)
+				return
+			}
+			if strings.HasPrefix(r.URL.Path, basePath+"/") {
+				r = r.Clone(r.Context())
+				r.URL.Path = strings.TrimPrefix(r.URL.Path, basePath)
+				r.URL.RawPath = ""
+			}
+		}
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), basePathKey{}, basePath)))
+	})
+}
+
+// base base path of the request for links, empty if served at the root
+func base(r *http.Request) string {
+	if basePath, ok := r.Context().Value(basePathKey{}).(string); ok {
+		return basePath
+	}
+	return ""
+}
+
+// isHTTPS request was sent with https, directly or to a trusted proxy
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.URL.Scheme == "https"
+}
+
+// cookiePath path of the cookies, limited to the base path
+func cookiePath(r *http.Request) string {
+	return base(r) + "/"
+}
+
+func cleanBasePath(basePath string) string {
+	basePath = strings.Trim(strings.TrimSpace(basePath), "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
+
+// forwardedFor client address of the X-Forwarded-For header, the last
+// entry is added by the trusted proxy, entries before it are sent by the
+// client and can be forged
+func forwardedFor(r *http.Request) string {
+	values := r.Header.Values("X-Forwarded-For")
+	if len(values) == 0 {
+		return ""
+	}
+	entries := strings.Split(values[len(values)-1], ",")
+	ip := strings.TrimSpace(entries[len(entries)-1])
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+	return ip
+}
@@ -0,0 +1,31 @@
+package sgphelper
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestForwardedFor(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		headers []string
+		want    string
+	}{
+		{"none", nil, ""},
+		{"single", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"spoofed client entry", []string{"10.0.0.1, 203.0.113.7"}, "203.0.113.7"},
+		{"several headers", []string{"10.0.0.1", "198.51.100.2, 203.0.113.7"}, "203.0.113.7"},
+		{"ipv6", []string{"2001:db8::1"}, "2001:db8::1"},
+		{"invalid last entry", []string{"203.0.113.7, unknown"}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			for _, h := range tc.headers {
+				r.Header.Add("X-Forwarded-For", h)
+			}
+			if got := forwardedFor(r); got != tc.want {
+				t.Errorf("forwarded for %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
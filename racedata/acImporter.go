@@ -0,0 +1,131 @@
+package racedata
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// AC_FORMAT name of the assetto corsa server result json format
+const AC_FORMAT = "ac"
+
+type acResultFile struct {
+	Type   string     `json:"Type"`
+	Cars   []acCar    `json:"Cars"`
+	Result []acResult `json:"Result"`
+	Laps   []acLap    `json:"Laps"`
+}
+
+type acCar struct {
+	CarId  int      `json:"CarId"`
+	Model  string   `json:"Model"`
+	Driver acDriver `json:"Driver"`
+}
+
+type acDriver struct {
+	Name string `json:"Name"`
+	Team string `json:"Team"`
+	Guid string `json:"Guid"`
+}
+
+type acResult struct {
+	DriverName string `json:"DriverName"`
+	DriverGuid string `json:"DriverGuid"`
+	CarId      int    `json:"CarId"`
+	CarModel   string `json:"CarModel"`
+	BestLap    int    `json:"BestLap"`
+	TotalTime  int    `json:"TotalTime"`
+	// GridPosition only written by server managers, the vanilla
+	// server result has no grid, the start position stays empty then
+	GridPosition int `json:"GridPosition"`
+}
+
+type acLap struct {
+	CarId   int `json:"CarId"`
+	LapTime int `json:"LapTime"`
+	Cuts    int `json:"Cuts"`
+}
+
+// acImporter assetto corsa dedicated server results, the result list
+// is already ordered by finishing position, lap counts and clean laps
+// are taken from the lap list and start positions from the grid position
+// if the server wrote one
+type acImporter struct{}
+
+func (acImporter) Name() string {
+	return AC_FORMAT
+}
+
+func (acImporter) Import(data []byte) (*CSVResult, error) {
+	var file acResultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("can not parse assetto corsa result - %v", err)
+	}
+
+	if len(file.Result) == 0 {
+		return nil, fmt.Errorf("assetto corsa result contains no drivers")
+	}
+
+	cars := map[int]acCar{}
+	for _, car := range file.Cars {
+		cars[car.CarId] = car
+	}
+
+	laps := map[int]int{}
+	bestClean := map[int]int{}
+	for _, lap := range file.Laps {
+		laps[lap.CarId]++
+		if lap.Cuts != 0 {
+			continue
+		}
+		if best, found := bestClean[lap.CarId]; !found || lap.LapTime < best {
+			bestClean[lap.CarId] = lap.LapTime
+		}
+	}
+
+	result := CSVResult{}
+	for _, r := range file.Result {
+		// empty slots are listed with a zero total time and no driver
+		if r.DriverName == "" && r.DriverGuid == "" {
+			continue
+		}
+
+		car := cars[r.CarId]
+		participant := car.Driver.Team
+		if participant == "" {
+			participant = r.DriverName
+		}
+		model := r.CarModel
+		if model == "" {
+			model = car.Model
+		}
+
+		startPos := ""
+		if r.GridPosition > 0 {
+			startPos = strconv.Itoa(r.GridPosition)
+		}
+
+		result = append(result, CSVResultLine{
+			Pos:              uint(len(result) + 1),
+			StartPos:         startPos,
+			Participant:      participant,
+			Driver:           r.DriverName,
+			Car:              model,
+			Class:            DEFAULT_CLASS,
+			TotalTime:        acTime(r.TotalTime),
+			BestLapTime:      acTime(r.BestLap),
+			BestCleanLapTime: acTime(bestClean[r.CarId]),
+			Laps:             strconv.Itoa(laps[r.CarId]),
+		})
+	}
+
+	return &result, nil
+}
+
+// acTime assetto corsa uses milliseconds and 999999999 for missing laps
+func acTime(milliseconds int) string {
+	if milliseconds <= 0 || milliseconds >= 999999999 {
+		return ""
+	}
+	return strconv.Itoa(milliseconds)
+}
@@ -0,0 +1,109 @@
+package racedata
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// RaceAwards fastest lap and fastest clean lap of a race split
+type RaceAwards struct {
+	Race            string      `json:"race"`
+	Split           string      `json:"split"`
+	FastestLap      *ResultLine `json:"fastest_lap,omitempty"`
+	FastestCleanLap *ResultLine `json:"fastest_clean_lap,omitempty"`
+}
+
+// markFastestLaps flag the lines with the fastest lap and the fastest
+// clean lap, lap times in milliseconds, the first line wins a tie
+func markFastestLaps(lines ResultLines) {
+	fastest, fastestClean := -1, -1
+	best, bestClean := 0, 0
+	for i, line := range lines {
+		if t, err := strconv.Atoi(line.BestLapTime); err == nil && t > 0 && (fastest == -1 || t < best) {
+			fastest, best = i, t
+		}
+		if t, err := strconv.Atoi(line.BestCleanLapTime); err == nil && t > 0 && (fastestClean == -1 || t < bestClean) {
+			fastestClean, bestClean = i, t
+		}
+	}
+	if fastest != -1 {
+		lines[fastest].FastestLap = true
+	}
+	if fastestClean != -1 {
+		lines[fastestClean].FastestCleanLap = true
+	}
+}
+
+// fastestLaps lines flagged with the fastest lap and fastest clean lap per split
+func fastestLaps(result map[string]ResultLines) (map[string]ResultLine, map[string]ResultLine) {
+	fastest := map[string]ResultLine{}
+	fastestClean := map[string]ResultLine{}
+	for split, lines := range result {
+		for _, line := range lines {
+			if line.FastestLap {
+				fastest[split] = line
+			}
+			if line.FastestCleanLap {
+				fastestClean[split] = line
+			}
+		}
+	}
+	return fastest, fastestClean
+}
+
+// convertLapTime format lap times in milliseconds, other values are kept
+func convertLapTime(milliseconds string) string {
+	if t, err := strconv.Atoi(milliseconds); err != nil || t <= 0 {
+		return milliseconds
+	}
+	return convertMilliseconds(milliseconds)
+}
+
+// sortedKeys split names in alphabetical order
+func sortedKeys(result map[string]ResultLines) []string {
+	keys := make([]string, 0, len(result))
+	for k := range result {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func csvFlag(b bool) string {
+	if b {
+		return "x"
+	}
+	return ""
+}
+
+// GetSeasonAwards fastest lap and fastest clean lap of every race split
+// with results, in race order
+func (s *RaceData) GetSeasonAwards(seasonName string) ([]RaceAwards, error) {
+	season, found := s.Seasons[seasonName]
+	if !found {
+		return nil, fmt.Errorf("season %v %w", seasonName, ErrNotFound)
+	}
+
+	awards := []RaceAwards{}
+	for _, race := range season.Races {
+		if race.RaceResultFile == "" {
+			continue
+		}
+		raceResult, err := s.GetRaceResult(seasonName, race.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, split := range sortedKeys(raceResult.RaceResultWithPenalty) {
+			a := RaceAwards{Race: race.Name, Split: split}
+			if line, found := raceResult.FastestLap[split]; found {
+				a.FastestLap = &line
+			}
+			if line, found := raceResult.FastestCleanLap[split]; found {
+				a.FastestCleanLap = &line
+			}
+			awards = append(awards, a)
+		}
+	}
+	return awards, nil
+}
@@ -0,0 +1,267 @@
+package racedata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// BRANDING_FILE instance branding, stored next to the race data file
+const BRANDING_FILE = "branding.json"
+
+// DEFAULT_BRAND_NAME name shown without branding
+const DEFAULT_BRAND_NAME = "sgp helper"
+
+// MAX_BRAND_NAME_LENGTH max length of the league name
+const MAX_BRAND_NAME_LENGTH = 100
+
+// ErrInvalidBranding colour, link or logo can not be used
+var ErrInvalidBranding = errors.New("is not valid branding")
+
+// logoTypes accepted logo content types and their file extension,
+// svg is not accepted as it can contain scripts
+var logoTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var colorPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Branding league name, logo, colours and footer links of the instance
+// or a season, empty season fields fall back to the instance
+type Branding struct {
+	Name           string `json:"name,omitempty"`
+	LogoFile       string `json:"logo_file,omitempty"`
+	PrimaryColor   string `json:"primary_color,omitempty"`
+	SecondaryColor string `json:"secondary_color,omitempty"`
+	FooterLinks    []Link `json:"footer_links,omitempty"`
+}
+
+type Link struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// IsZero no branding field is set
+func (b Branding) IsZero() bool {
+	return b.Name == "" && b.LogoFile == "" && b.PrimaryColor == "" && b.SecondaryColor == "" && len(b.FooterLinks) == 0
+}
+
+// merge season branding over the instance branding
+func (b Branding) merge(season Branding) Branding {
+	if season.Name != "" {
+		b.Name = season.Name
+	}
+	if season.LogoFile != "" {
+		b.LogoFile = season.LogoFile
+	}
+	if season.PrimaryColor != "" {
+		b.PrimaryColor = season.PrimaryColor
+	}
+	if season.SecondaryColor != "" {
+		b.SecondaryColor = season.SecondaryColor
+	}
+	if len(season.FooterLinks) > 0 {
+		b.FooterLinks = season.FooterLinks
+	}
+	return b
+}
+
+// check validate name, colours and links, the logo is set with SetLogo
+func (b Branding) check() error {
+	if len(b.Name) > MAX_BRAND_NAME_LENGTH {
+		return fmt.Errorf("name longer than %v characters %w", MAX_BRAND_NAME_LENGTH, ErrInvalidBranding)
+	}
+	for _, color := range []string{b.PrimaryColor, b.SecondaryColor} {
+		if color != "" && !colorPattern.MatchString(color) {
+			return fmt.Errorf("colour %q %w, use #rgb or #rrggbb", color, ErrInvalidBranding)
+		}
+	}
+	for _, link := range b.FooterLinks {
+		if strings.TrimSpace(link.Title) == "" {
+			return fmt.Errorf("footer link %v without title %w", link.URL, ErrInvalidBranding)
+		}
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && !(u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/"))) {
+			return fmt.Errorf("footer link %q %w, use an http(s) url or an absolute path", link.URL, ErrInvalidBranding)
+		}
+	}
+	return nil
+}
+
+// GetBranding branding of the season merged with the instance branding,
+// the instance branding if seasonName is empty
+func (s *RaceData) GetBranding(seasonName string) Branding {
+	b := s.branding
+	if season, found := s.Seasons[seasonName]; found && season.Branding != nil {
+		b = b.merge(*season.Branding)
+	}
+	if b.Name == "" {
+		b.Name = DEFAULT_BRAND_NAME
+	}
+	return b
+}
+
+// GetOwnBranding branding set for the season without the instance
+// fallback, the instance branding if seasonName is empty
+func (s *RaceData) GetOwnBranding(seasonName string) (Branding, error) {
+	if seasonName == "" {
+		return s.branding, nil
+	}
+	season, found := s.Seasons[seasonName]
+	if !found {
+		return Branding{}, fmt.Errorf("season %v %w", seasonName, ErrNotFound)
+	}
+	if season.Branding == nil {
+		return Branding{}, nil
+	}
+	return *season.Branding, nil
+}
+
+// SetBranding set name, colours and footer links of the season or
+// the instance if seasonName is empty, the logo is kept
+func (s *RaceData) SetBranding(seasonName string, b Branding) error {
+	b.Name = strings.TrimSpace(b.Name)
+	if err := b.check(); err != nil {
+		return err
+	}
+	return s.updateBranding(seasonName, func(current *Branding) error {
+		b.LogoFile = current.LogoFile
+		*current = b
+		return nil
+	})
+}
+
+// CheckLogo only png, jpeg, gif and webp logos are accepted
+func CheckLogo(logo []byte) error {
+	if _, found := logoTypes[http.DetectContentType(logo)]; !found {
+		return fmt.Errorf("logo %w, use png, jpeg, gif or webp", ErrInvalidBranding)
+	}
+	return nil
+}
+
+// SetLogo store the png, jpeg, gif or webp logo of the season
+// or the instance if seasonName is empty
+func (s *RaceData) SetLogo(seasonName string, logo []byte) error {
+	if err := CheckLogo(logo); err != nil {
+		return err
+	}
+	ext := logoTypes[http.DetectContentType(logo)]
+
+	return s.updateBranding(seasonName, func(current *Branding) error {
+		dir := s.DataDir
+		if seasonName != "" {
+			dir = s.seasonDir(s.Seasons[seasonName])
+		}
+		if err := os.MkdirAll(dir, 0770); err != nil {
+			return err
+		}
+		logoFile := path.Join(dir, "logo"+ext)
+		s.logger.Info("set logo", "season", seasonName, "file", logoFile)
+		if err := os.WriteFile(logoFile, logo, 0644); err != nil {
+			return err
+		}
+		if current.LogoFile != "" && current.LogoFile != logoFile {
+			s.removeLogoFile(current.LogoFile)
+		}
+		current.LogoFile = logoFile
+		return nil
+	})
+}
+
+// RemoveLogo remove the logo of the season or the instance
+func (s *RaceData) RemoveLogo(seasonName string) error {
+	return s.updateBranding(seasonName, func(current *Branding) error {
+		if current.LogoFile != "" {
+			s.removeLogoFile(current.LogoFile)
+		}
+		current.LogoFile = ""
+		return nil
+	})
+}
+
+// GetLogoFile logo file of the season, the instance logo if the
+// season has none, empty if there is no logo
+func (s *RaceData) GetLogoFile(seasonName string) (string, error) {
+	logoFile := s.GetBranding(seasonName).LogoFile
+	if logoFile == "" {
+		return "", nil
+	}
+	if err := s.checkDataFile(logoFile); err != nil {
+		return "", err
+	}
+	return logoFile, nil
+}
+
+func (s *RaceData) updateBranding(seasonName string, update func(current *Branding) error) error {
+	if seasonName == "" {
+		if err := update(&s.branding); err != nil {
+			return err
+		}
+		s.writeBrandingFile()
+		return nil
+	}
+
+	season, found := s.Seasons[seasonName]
+	if !found {
+		return fmt.Errorf("season %v %w", seasonName, ErrNotFound)
+	}
+	b := Branding{}
+	if season.Branding != nil {
+		b = *season.Branding
+	}
+	if err := update(&b); err != nil {
+		return err
+	}
+	season.Branding = &b
+	if b.IsZero() {
+		season.Branding = nil
+	}
+	s.Seasons[seasonName] = season
+	s.writeSeasonsFile()
+	return nil
+}
+
+// removeLogoFile remove a replaced logo, a left over file is only logged
+func (s *RaceData) removeLogoFile(logoFile string) {
+	if err := os.Remove(logoFile); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		s.logger.Warn("can not remove logo", "file", logoFile, "error", err)
+	}
+}
+
+// brandingFile instance branding file in the directory of the race data file
+func (s *RaceData) brandingFile() string {
+	return path.Join(path.Dir(s.RaceDataFile), BRANDING_FILE)
+}
+
+func (s *RaceData) readBrandingFile() {
+	b, err := os.ReadFile(s.brandingFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		fatal(s.logger, "can not read branding file", err)
+	}
+	if err := json.Unmarshal(b, &s.branding); err != nil {
+		fatal(s.logger, "can not decode branding file", err)
+	}
+}
+
+func (s *RaceData) writeBrandingFile() {
+	b, err := json.MarshalIndent(s.branding, "", "   ")
+	if err != nil {
+		fatal(s.logger, "can not encode branding", err)
+	}
+	if err := os.WriteFile(s.brandingFile(), b, 0644); err != nil {
+		fatal(s.logger, "can not write branding file", err)
+	}
+}
@@ -0,0 +1,149 @@
+package racedata
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	testPNG = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR")
+	testGIF = []byte("GIF89a\x01\x00\x01\x00")
+	testSVG = []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`)
+)
+
+func TestBrandingCheck(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		branding Branding
+		valid    bool
+	}{
+		{"empty", Branding{}, true},
+		{"complete", Branding{Name: "GT League", PrimaryColor: "#1a2B3c", SecondaryColor: "#fff",
+			FooterLinks: []Link{{"Discord", "https://discord.example/gt"}, {"Rules", "/rules"}}}, true},
+		{"long name", Branding{Name: strings.Repeat("x", MAX_BRAND_NAME_LENGTH+1)}, false},
+		{"colour name", Branding{PrimaryColor: "red"}, false},
+		{"colour length", Branding{SecondaryColor: "#ffff"}, false},
+		{"colour css", Branding{PrimaryColor: "#fff;background:url(x)"}, false},
+		{"javascript link", Branding{FooterLinks: []Link{{"x", "javascript:alert(1)"}}}, false},
+		{"protocol relative link", Branding{FooterLinks: []Link{{"x", "//evil.example"}}}, false},
+		{"relative link", Branding{FooterLinks: []Link{{"x", "rules"}}}, false},
+		{"link without title", Branding{FooterLinks: []Link{{" ", "https://example.com"}}}, false},
+	} {
+		err := tc.branding.check()
+		if tc.valid && err != nil {
+			t.Errorf("%v: unexpected error %v", tc.name, err)
+		}
+		if !tc.valid && !errors.Is(err, ErrInvalidBranding) {
+			t.Errorf("%v: error %v, want %v", tc.name, err, ErrInvalidBranding)
+		}
+	}
+}
+
+func TestSetBranding(t *testing.T) {
+	rd := newTestRaceData(t)
+
+	if err := rd.SetBranding("", Branding{Name: " GT League ", PrimaryColor: "#111111", SecondaryColor: "#222222",
+		FooterLinks: []Link{{"Discord", "https://discord.example"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.SetLogo("Season 1", testPNG); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.SetBranding("Season 1", Branding{PrimaryColor: "#333"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.SetBranding("Season 9", Branding{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown season: error %v, want %v", err, ErrNotFound)
+	}
+	if err := rd.SetBranding("", Branding{PrimaryColor: "blue"}); !errors.Is(err, ErrInvalidBranding) {
+		t.Errorf("invalid colour: error %v, want %v", err, ErrInvalidBranding)
+	}
+
+	// season fields over the instance fields, the logo is kept by SetBranding
+	b := rd.GetBranding("Season 1")
+	if b.Name != "GT League" || b.PrimaryColor != "#333" || b.SecondaryColor != "#222222" || len(b.FooterLinks) != 1 {
+		t.Errorf("unexpected merged branding %+v", b)
+	}
+	if filepath.Base(b.LogoFile) != "logo.png" {
+		t.Errorf("season logo %v was not kept", b.LogoFile)
+	}
+	own, err := rd.GetOwnBranding("Season 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if own.Name != "" || own.PrimaryColor != "#333" {
+		t.Errorf("unexpected season branding %+v", own)
+	}
+
+	// the instance branding is stored next to the race data file
+	reloaded := NewRaceData(rd.DataDir, rd.RaceDataFile, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if b := reloaded.GetBranding(""); b.Name != "GT League" || b.PrimaryColor != "#111111" {
+		t.Errorf("branding after reload %+v", b)
+	}
+	if b := reloaded.GetBranding("Season 1"); b.PrimaryColor != "#333" {
+		t.Errorf("season branding after reload %+v", b)
+	}
+}
+
+func TestDefaultBranding(t *testing.T) {
+	rd := newTestRaceData(t)
+	if b := rd.GetBranding("Season 1"); b.Name != DEFAULT_BRAND_NAME || b.LogoFile != "" || b.PrimaryColor != "" {
+		t.Errorf("unexpected default branding %+v", b)
+	}
+	if logo, err := rd.GetLogoFile(""); logo != "" || err != nil {
+		t.Errorf("logo %q %v without branding", logo, err)
+	}
+}
+
+func TestSetLogo(t *testing.T) {
+	rd := newTestRaceData(t)
+
+	if err := rd.SetLogo("", testSVG); !errors.Is(err, ErrInvalidBranding) {
+		t.Errorf("svg logo: error %v, want %v", err, ErrInvalidBranding)
+	}
+	if err := rd.SetLogo("Season 9", testPNG); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown season: error %v, want %v", err, ErrNotFound)
+	}
+
+	if err := rd.SetLogo("", testPNG); err != nil {
+		t.Fatal(err)
+	}
+	pngLogo := filepath.Join(rd.DataDir, "logo.png")
+	if logo, err := rd.GetLogoFile("Season 1"); logo != pngLogo || err != nil {
+		t.Errorf("season logo %v %v, want the instance logo %v", logo, err, pngLogo)
+	}
+
+	// a replaced logo of another type is removed
+	if err := rd.SetLogo("", testGIF); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(pngLogo); !os.IsNotExist(err) {
+		t.Errorf("replaced logo %v was kept", pngLogo)
+	}
+
+	if err := rd.SetLogo("Season 1", testPNG); err != nil {
+		t.Fatal(err)
+	}
+	seasonLogo := filepath.Join(rd.seasonDir(rd.Seasons["Season 1"]), "logo.png")
+	if logo, _ := rd.GetLogoFile("Season 1"); logo != seasonLogo {
+		t.Errorf("season logo %v, want %v", logo, seasonLogo)
+	}
+
+	if err := rd.RemoveLogo("Season 1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(seasonLogo); !os.IsNotExist(err) {
+		t.Errorf("removed logo %v was kept", seasonLogo)
+	}
+	if rd.Seasons["Season 1"].Branding != nil {
+		t.Errorf("empty season branding was stored %+v", rd.Seasons["Season 1"].Branding)
+	}
+	if logo, _ := rd.GetLogoFile("Season 1"); filepath.Base(logo) != "logo.gif" {
+		t.Errorf("logo %v, want the instance gif", logo)
+	}
+}
@@ -1,27 +1,25 @@
 package racedata
 
 import (
+	"bytes"
 	"encoding/csv"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/artyom/csvstruct"
 )
 
-const TOTAL_TIME_COLUMN = 5
-const POS_COLUMN = 0
-const PENALTY_COLUMN = 11
-
 type CSVEntryListLine struct {
 	Driver     string `csv:"driver"`
 	Team       string `csv:"team"`
 	Car        string `csv:"car"`
 	RaceNumber string `csv:"race_number"`
 	Class      string `csv:"class"`
+	DriverID   string `csv:"driver_id"`
 }
 
 type CSVEntryList []CSVEntryListLine
@@ -37,41 +35,38 @@ type CSVResultLine struct {
 	BestCleanLapTime string `csv:"bestCleanLapTime"`
 	Laps             string `csv:"laps"`
 	Penalty          string `csv:"penalty"`
+	CarNumber        string `csv:"carNumber"`
+	Driver           string `csv:"driver"`
 }
 
 type CSVResult []CSVResultLine
 
 func readEntryList(entryListFilename string) (*CSVEntryList, error) {
-	log.Printf("read entry list: %v\n", entryListFilename)
-	file, err := os.Open(entryListFilename)
+	slog.Debug("read entry list", "file", entryListFilename)
+	data, err := os.ReadFile(entryListFilename)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	header, err := reader.Read()
+	return parseEntryList(data, nil)
+}
+
+func parseEntryList(data []byte, profile *CSVProfile) (*CSVEntryList, error) {
+	records, err := decodeCSV(data, profile, requiredEntryListColumns)
 	if err != nil {
 		return nil, err
 	}
 
-	scan, err := csvstruct.NewScanner(header, &CSVEntryListLine{})
+	scan, err := csvstruct.NewScanner(records[0], &CSVEntryListLine{})
 	if err != nil {
-		log.Fatalf("new scanner for header %v - %v", header, err)
+		return nil, fmt.Errorf("new scanner for header %v - %v", records[0], err)
 	}
 
 	lines := CSVEntryList{}
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
+	for _, record := range records[1:] {
 		var line CSVEntryListLine
 		if err := scan(record, &line); err != nil {
-			log.Fatalf("can not parse line %v - %v", record, err)
+			return nil, fmt.Errorf("can not parse line %v - %v", record, err)
 		}
 		lines = append(lines, line)
 	}
@@ -79,36 +74,46 @@ func readEntryList(entryListFilename string) (*CSVEntryList, error) {
 	return &lines, nil
 }
 
+// encodeEntryList write the entry list as csv with the default column names
+func encodeEntryList(entryList *EntryList) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+
+	records := [][]string{{"driver", "team", "car", "race_number", "class", "driver_id"}}
+	for _, d := range *entryList {
+		records = append(records, []string{d.Driver, d.Team, d.Car, d.RaceNumber, d.Class, d.DriverID})
+	}
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func readResult(resultFilename string) (*CSVResult, error) {
-	file, err := os.Open(resultFilename)
+	data, err := os.ReadFile(resultFilename)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	reader := csv.NewReader(file)
-	header, err := reader.Read()
+	return parseResult(data, nil)
+}
+
+func parseResult(data []byte, profile *CSVProfile) (*CSVResult, error) {
+	records, err := decodeCSV(data, profile, requiredResultColumns)
 	if err != nil {
 		return nil, err
 	}
 
-	scan, err := csvstruct.NewScanner(header, &CSVResultLine{})
+	scan, err := csvstruct.NewScanner(records[0], &CSVResultLine{})
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("new scanner for header %v - %v", records[0], err)
 	}
 
 	result := CSVResult{}
-	for {
-		record, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
+	for _, record := range records[1:] {
 		var line CSVResultLine
 		if err := scan(record, &line); err != nil {
-			log.Fatal(err)
+			return nil, fmt.Errorf("can not parse line %v - %v", record, err)
 		}
 		result = append(result, line)
 	}
@@ -123,7 +128,7 @@ func convertMilliseconds(milliseconds string) string {
 
 	m, err := strconv.Atoi(milliseconds)
 	if err != nil {
-		log.Fatal(err)
+		fatal(slog.Default(), "can not convert milliseconds", err)
 	}
 	d := time.Duration(m) * time.Millisecond
 
@@ -136,9 +141,10 @@ func convertMilliseconds(milliseconds string) string {
 
 }
 
-func checkEntryListUnique(filename string) error {
+// checkEntryListUnique team names of the normalized entry list must be unique
+func checkEntryListUnique(data []byte) error {
 
-	entryList, err := readEntryList(filename)
+	entryList, err := parseEntryList(data, nil)
 	if err != nil {
 		return err
 	}
@@ -147,19 +153,24 @@ func checkEntryListUnique(filename string) error {
 	for _, line := range *entryList {
 		_, found := teamMap[line.Team]
 		if found {
-			log.Printf("team %v already exists in team map", line.Team)
+			slog.Warn("team already exists in entry list", "team", line.Team)
 		}
 		teamMap[line.Team] = line.Team
 	}
 	if len(teamMap) != len(*entryList) {
-		return fmt.Errorf("team names %v %v in entry list %v are not unique", len(teamMap), len(*entryList), filename)
+		return fmt.Errorf("team names %v %v in entry list are not unique", len(teamMap), len(*entryList))
 	}
-	log.Printf("entry list %v is ok\n", filename)
+	slog.Debug("entry list is ok")
 
 	return nil
 }
 
+// addPenaltyToResult add the penalty in seconds to the result line at pos,
+// negative penalties are rejected so a penalty can not be taken back by a typo
 func addPenaltyToResult(filename string, penalty string, pos string) error {
+	if seconds, err := strconv.Atoi(penalty); err != nil || seconds < 0 {
+		return fmt.Errorf("penalty %q %w, use a number of seconds not below 0", penalty, ErrInvalidValue)
+	}
 
 	file, err := os.Open(filename)
 	if err != nil {
@@ -172,23 +183,31 @@ func addPenaltyToResult(filename string, penalty string, pos string) error {
 	}
 	file.Close()
 
-	if err := os.Remove(filename); err != nil {
-		return err
+	if len(records) == 0 {
+		return fmt.Errorf("result file %v is empty", filename)
 	}
 
+	posColumn := columnIndex(records[0], "pos")
+	penaltyColumn := columnIndex(records[0], "penalty")
+	if posColumn == -1 || penaltyColumn == -1 {
+		return fmt.Errorf("result file %v has no pos or penalty column", filename)
+	}
+
+	found := false
 	for i := range records {
 		if i == 0 { // skip header line
 			continue
 		}
 
-		if records[i][POS_COLUMN] != pos {
+		if records[i][posColumn] != pos {
 			continue
 		}
+		found = true
 
-		if records[i][PENALTY_COLUMN] == "0" {
-			records[i][PENALTY_COLUMN] = penalty
+		if records[i][penaltyColumn] == "0" {
+			records[i][penaltyColumn] = penalty
 		} else {
-			penaltyInt, err := strconv.Atoi(records[i][PENALTY_COLUMN])
+			penaltyInt, err := strconv.Atoi(records[i][penaltyColumn])
 			if err != nil {
 				return err
 			}
@@ -197,12 +216,20 @@ func addPenaltyToResult(filename string, penalty string, pos string) error {
 			if err != nil {
 				return err
 			}
-			log.Printf("pos %v, penalty %v + %v = %v\n", pos, penaltyInt, addPenaltyInt, (addPenaltyInt + penaltyInt))
-			records[i][PENALTY_COLUMN] = fmt.Sprintf("%v", addPenaltyInt+penaltyInt)
+			slog.Debug("add penalty", "pos", pos, "penalty", penaltyInt, "add", addPenaltyInt, "total", addPenaltyInt+penaltyInt)
+			records[i][penaltyColumn] = fmt.Sprintf("%v", addPenaltyInt+penaltyInt)
 		}
 
 	}
 
+	if !found {
+		return fmt.Errorf("pos %v in result file %v %w", pos, filename, ErrNotFound)
+	}
+
+	if err := os.Remove(filename); err != nil {
+		return err
+	}
+
 	writeFile, err := os.Create(filename)
 	if err != nil {
 		return err
@@ -217,21 +244,20 @@ func addPenaltyToResult(filename string, penalty string, pos string) error {
 	return nil
 }
 
-func areTeamNamesUnique(filename string) error {
-	results, err := readResult(filename)
-	if err != nil {
-		return err
-	}
-
+func checkTeamNamesUnique(results *CSVResult) error {
 	var teamMap = make(map[string]string)
+	duplicates := []string{}
 	for _, result := range *results {
+		if _, found := teamMap[result.Participant]; found {
+			duplicates = append(duplicates, result.Participant)
+		}
 		teamMap[result.Participant] = result.Participant
 	}
 
-	log.Printf("team map length %v team result length %v", len(teamMap), len(*results))
+	slog.Debug("check team names", "teams", len(teamMap), "results", len(*results))
 
 	if len(teamMap) != len(*results) {
-		return fmt.Errorf("team names are not unique")
+		return fmt.Errorf("team names are not unique: %v", strings.Join(duplicates, ", "))
 	}
 	return nil
 }
@@ -249,18 +275,31 @@ func addPenaltyColumn(filename string) error {
 	}
 	file.Close()
 
-	if err := os.Remove(filename); err != nil {
-		return err
+	if len(records) == 0 {
+		return nil
 	}
 
-	for i := range records {
-		if i == 0 {
-			records[i] = append(records[i], "penalty")
-		} else {
-			records[i] = append(records[i], "0")
+	// re-imported exports already carry penalties, empty cells are no penalty
+	if penaltyColumn := columnIndex(records[0], "penalty"); penaltyColumn != -1 {
+		for i := range records[1:] {
+			if records[i+1][penaltyColumn] == "" {
+				records[i+1][penaltyColumn] = "0"
+			}
+		}
+	} else {
+		for i := range records {
+			if i == 0 {
+				records[i] = append(records[i], "penalty")
+			} else {
+				records[i] = append(records[i], "0")
+			}
 		}
 	}
 
+	if err := os.Remove(filename); err != nil {
+		return err
+	}
+
 	writeFile, err := os.Create(filename)
 	if err != nil {
 		return err
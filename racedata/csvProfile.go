@@ -0,0 +1,287 @@
+package racedata
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// required columns of result and entry list csv files after header mapping
+var requiredResultColumns = []string{"pos", "participant", "class", "totalTime", "laps"}
+var requiredEntryListColumns = []string{"driver", "team"}
+
+// CSVProfile header mapping for csv files which do not use the
+// simracing.gp column names, e.g. files saved by a localized excel
+type CSVProfile struct {
+	name string
+	// Delimiter field delimiter, detected from the header line if 0
+	Delimiter rune
+	// Columns source column name -> result or entry list field name
+	Columns map[string]string
+}
+
+var csvProfiles = map[string]CSVProfile{}
+
+// NewCSVProfile create a csv profile, delimiter may be empty for
+// auto detection, "tab" or a single character
+func NewCSVProfile(name string, delimiter string, columns map[string]string) (CSVProfile, error) {
+	profile := CSVProfile{name: name, Columns: map[string]string{}}
+
+	// profiles are registered as importers, the built-in ones are registered by init
+	if strings.TrimSpace(name) == "" {
+		return profile, fmt.Errorf("csv profile name is empty")
+	}
+	if _, found := importers[name]; found {
+		if _, isProfile := csvProfiles[name]; !isProfile {
+			return profile, fmt.Errorf("csv profile %v: name is reserved for the built-in result format", name)
+		}
+	}
+
+	switch {
+	case delimiter == "":
+	case delimiter == "tab" || delimiter == "\\t":
+		profile.Delimiter = '\t'
+	case utf8.RuneCountInString(delimiter) == 1:
+		profile.Delimiter, _ = utf8.DecodeRuneInString(delimiter)
+	default:
+		return profile, fmt.Errorf("csv profile %v: delimiter %q must be a single character", name, delimiter)
+	}
+
+	for source, field := range columns {
+		if !isKnownColumn(field) {
+			return profile, fmt.Errorf("csv profile %v: column %v maps to unknown field %v", name, source, field)
+		}
+		profile.Columns[strings.ToLower(strings.TrimSpace(source))] = field
+	}
+	return profile, nil
+}
+
+// RegisterCSVProfile make the profile available for entry lists and
+// as result importer with the profile name as format
+func RegisterCSVProfile(profile CSVProfile) {
+	csvProfiles[profile.name] = profile
+	RegisterImporter(profile)
+}
+
+// GetCSVProfile profile registered with name, empty name is the default profile
+func GetCSVProfile(name string) (*CSVProfile, error) {
+	if name == "" {
+		return nil, nil
+	}
+	profile, found := csvProfiles[name]
+	if !found {
+		return nil, fmt.Errorf("csv profile %v not found", name)
+	}
+	return &profile, nil
+}
+
+// CSVProfileNames sorted names of all registered csv profiles
+func CSVProfileNames() []string {
+	names := []string{}
+	for name := range csvProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p CSVProfile) Name() string {
+	return p.name
+}
+
+func (p CSVProfile) Import(data []byte) (*CSVResult, error) {
+	return parseResult(data, &p)
+}
+
+// knownColumns csv tags of CSVResultLine, CSVEntryListLine and CSVLapLine
+func knownColumns() []string {
+	return []string{
+		"pos", "startPos", "participant", "car", "class", "totalTime", "bestLapTime",
+		"bestCleanLapTime", "laps", "penalty", "carNumber", "driver",
+		"team", "race_number", "driver_id", "lap", "lapTime",
+	}
+}
+
+func isKnownColumn(name string) bool {
+	for _, c := range knownColumns() {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// mapHeader rename header columns to field names, profile mapping first,
+// case insensitive match of the field names second, unknown columns are kept
+func mapHeader(header []string, profile *CSVProfile) []string {
+	mapped := make([]string, len(header))
+	for i, h := range header {
+		key := strings.ToLower(strings.TrimSpace(h))
+		mapped[i] = strings.TrimSpace(h)
+
+		if profile != nil {
+			if field, found := profile.Columns[key]; found {
+				mapped[i] = field
+				continue
+			}
+		}
+		for _, c := range knownColumns() {
+			if strings.ToLower(c) == key {
+				mapped[i] = c
+				break
+			}
+		}
+	}
+	return mapped
+}
+
+func checkRequiredColumns(header []string, required []string) error {
+	missing := []string{}
+	for _, r := range required {
+		if columnIndex(header, r) == -1 {
+			missing = append(missing, r)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required columns: %v (found columns: %v)",
+			strings.Join(missing, ", "), strings.Join(header, ", "))
+	}
+	return nil
+}
+
+func columnIndex(header []string, name string) int {
+	for i, h := range header {
+		if h == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// decodeCSV detect encoding and delimiter, map the header and check
+// the required columns, the first returned record is the mapped header
+func decodeCSV(data []byte, profile *CSVProfile, required []string) ([][]string, error) {
+	text := toUTF8(data)
+
+	delimiter := rune(0)
+	if profile != nil {
+		delimiter = profile.Delimiter
+	}
+	if delimiter == 0 {
+		delimiter = detectDelimiter(text)
+	}
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("csv file is empty")
+	}
+
+	records[0] = mapHeader(records[0], profile)
+	if err := checkRequiredColumns(records[0], required); err != nil {
+		return nil, err
+	}
+
+	// drop empty lines excel likes to append and pad short lines
+	lines := records[:1]
+	for _, record := range records[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		for i := range record {
+			record[i] = strings.TrimSpace(record[i])
+		}
+		for len(record) < len(records[0]) {
+			record = append(record, "")
+		}
+		lines = append(lines, record)
+	}
+	return lines, nil
+}
+
+// normalizeCSV convert csv data into the comma separated utf-8 format
+// with mapped header used for files in the data directory
+func normalizeCSV(data []byte, profile *CSVProfile, required []string) ([]byte, error) {
+	records, err := decodeCSV(data, profile, required)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	if err := w.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// detectDelimiter pick the most frequent of comma, semicolon and tab
+// in the header line
+func detectDelimiter(text string) rune {
+	line := text
+	if i := strings.IndexAny(text, "\r\n"); i != -1 {
+		line = text[:i]
+	}
+
+	delimiter := ','
+	count := strings.Count(line, ",")
+	for _, d := range []rune{';', '\t'} {
+		if c := strings.Count(line, string(d)); c > count {
+			delimiter = d
+			count = c
+		}
+	}
+	return delimiter
+}
+
+// toUTF8 strip byte order marks and convert utf-16 and windows-1252
+// encoded data to utf-8
+func toUTF8(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:])
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return decodeUTF16(data[2:], false)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return decodeUTF16(data[2:], true)
+	case utf8.Valid(data):
+		return string(data)
+	}
+
+	var sb strings.Builder
+	for _, b := range data {
+		if b >= 0x80 && b < 0xA0 && cp1252[b-0x80] != 0 {
+			sb.WriteRune(cp1252[b-0x80])
+		} else {
+			sb.WriteRune(rune(b))
+		}
+	}
+	return sb.String()
+}
+
+func decodeUTF16(data []byte, bigEndian bool) string {
+	u := make([]uint16, len(data)/2)
+	for i := range u {
+		if bigEndian {
+			u[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		} else {
+			u[i] = uint16(data[2*i+1])<<8 | uint16(data[2*i])
+		}
+	}
+	return string(utf16.Decode(u))
+}
+
+// cp1252 windows-1252 characters in the range 0x80 - 0x9f
+var cp1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
@@ -0,0 +1,115 @@
+package racedata
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestNewCSVProfileReservedNames(t *testing.T) {
+	for _, name := range []string{SGP_FORMAT, AC_FORMAT, RF2_FORMAT, ""} {
+		if _, err := NewCSVProfile(name, "", nil); err == nil {
+			t.Errorf("profile name %q accepted", name)
+		}
+	}
+	if _, err := NewCSVProfile("excel", ";", map[string]string{"Fahrer": "driver"}); err != nil {
+		t.Error(err)
+	}
+}
+
+// utf16LE utf-16 little endian with byte order mark
+func utf16LE(text string) []byte {
+	b := []byte{0xFF, 0xFE}
+	for _, u := range utf16.Encode([]rune(text)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+func utf16BE(text string) []byte {
+	b := []byte{0xFE, 0xFF}
+	for _, u := range utf16.Encode([]rune(text)) {
+		b = append(b, byte(u>>8), byte(u))
+	}
+	return b
+}
+
+func TestParseEntryListEncodings(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		{"comma", []byte("driver,team\nJürgen Müller,Team Ö\n")},
+		{"semicolon", []byte("driver;team\nJürgen Müller;Team Ö\n")},
+		{"tab", []byte("driver\tteam\nJürgen Müller\tTeam Ö\n")},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, "driver;team\nJürgen Müller;Team Ö\n"...)},
+		{"utf-16le", utf16LE("driver;team\r\nJürgen Müller;Team Ö\r\n")},
+		{"utf-16be", utf16BE("driver,team\nJürgen Müller,Team Ö\n")},
+		{"cp1252", []byte("driver;team\nJ\xfcrgen M\xfcller;Team \xd6\n")},
+		{"case insensitive header", []byte("Driver;TEAM\nJürgen Müller;Team Ö\n")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			entryList, err := parseEntryList(tc.data, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(*entryList) != 1 {
+				t.Fatalf("got %v lines, want 1", len(*entryList))
+			}
+			if line := (*entryList)[0]; line.Driver != "Jürgen Müller" || line.Team != "Team Ö" {
+				t.Errorf("got %q %q", line.Driver, line.Team)
+			}
+		})
+	}
+}
+
+func TestDetectDelimiter(t *testing.T) {
+	for text, want := range map[string]rune{
+		"driver,team\n1;2;3\n":   ',',
+		"driver;team;car\n1,2\n": ';',
+		"driver\tteam\r\n":       '\t',
+		"driver":                 ',',
+	} {
+		if got := detectDelimiter(text); got != want {
+			t.Errorf("%q: got %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestCP1252(t *testing.T) {
+	if got := toUTF8([]byte("\x80 \x93quoted\x94 \x9e")); got != "€ “quoted” ž" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestCSVProfileMapping(t *testing.T) {
+	profile, err := NewCSVProfile("german", "", map[string]string{
+		"Platz": "pos", "Team": "participant", "Klasse": "class", "Gesamtzeit": "totalTime", "Runden": "laps",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := profile.Import([]byte("Platz;Team;Klasse;Gesamtzeit;Runden\n1;Team A;GT3;3600000;30\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line := (*result)[0]; line.Pos != 1 || line.Participant != "Team A" || line.Class != "GT3" || line.TotalTime != "3600000" || line.Laps != "30" {
+		t.Errorf("unexpected line %+v", line)
+	}
+}
+
+func TestMissingRequiredColumns(t *testing.T) {
+	_, err := parseResult([]byte("pos;team;class\n1;Team A;GT3\n"), nil)
+	if err == nil {
+		t.Fatal("result without required columns accepted")
+	}
+	want := "missing required columns: participant, totalTime, laps (found columns: pos, team, class)"
+	if err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+
+	_, err = parseEntryList([]byte("name,car\nAnna,Porsche\n"), nil)
+	if err == nil || !strings.Contains(err.Error(), "missing required columns: driver, team") {
+		t.Errorf("unexpected entry list error %v", err)
+	}
+}
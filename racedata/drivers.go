@@ -0,0 +1,190 @@
+package racedata
+
+import (
+	"sort"
+	"strconv"
+)
+
+// UNKNOWN_DRIVER driver name of result lines without entry list entry
+const UNKNOWN_DRIVER = "N/A"
+
+// DriverResult result of a driver in a race split, Pos is the finishing
+// position in the split with penalties
+type DriverResult struct {
+	Season     string `json:"season"`
+	SeasonSlug string `json:"season_slug"`
+	Race       string `json:"race"`
+	RaceSlug   string `json:"race_slug"`
+	Split      string `json:"split"`
+	Driver     string `json:"driver"`
+	DriverID   string `json:"driver_id,omitempty"`
+	Team       string `json:"team"`
+	TeamID     string `json:"team_id,omitempty"`
+	StartPos   string `json:"start_pos"`
+	Pos        int    `json:"pos"`
+	Laps       string `json:"laps"`
+	Penalty    string `json:"penalty"`
+	Points     int    `json:"points"`
+	Pole       bool   `json:"pole"`
+	FastestLap bool   `json:"fastest_lap"`
+	// DNF the car completed less than half of the laps of the split winner
+	DNF bool `json:"dnf"`
+}
+
+// CareerStats statistics of the race results of a driver or team
+type CareerStats struct {
+	Starts      int `json:"starts"`
+	Wins        int `json:"wins"`
+	Podiums     int `json:"podiums"`
+	Poles       int `json:"poles"`
+	FastestLaps int `json:"fastest_laps"`
+	DNFs        int `json:"dnfs"`
+	Points      int `json:"points"`
+	// AverageFinish average finishing position of the races without DNF
+	AverageFinish float64 `json:"average_finish"`
+}
+
+// DriverProfile career of a registered driver over all seasons
+type DriverProfile struct {
+	ID          string   `json:"id"`
+	Driver      string   `json:"driver"`
+	Aliases     []string `json:"aliases,omitempty"`
+	SGPUserID   string   `json:"sgp_user_id,omitempty"`
+	Nationality string   `json:"nationality,omitempty"`
+	CareerStats
+	Results []DriverResult `json:"results"`
+}
+
+// GetDriverProfile every race result of the registered driver in all
+// seasons, seasons sorted by name and races in season order. Points are the
+// championship points of the race including the bonus points. The profile
+// of a merged duplicate is the profile of the driver it was merged into
+func (s *RaceData) GetDriverProfile(id string) (*DriverProfile, error) {
+	driver, err := s.GetDriver(id)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.careerResults(func(line ResultLine) bool {
+		return line.DriverID == driver.ID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &DriverProfile{
+		ID:          driver.ID,
+		Driver:      driver.Name,
+		Aliases:     driver.Aliases,
+		SGPUserID:   driver.SGPUserID,
+		Nationality: driver.Nationality,
+		Results:     results,
+	}
+	profile.CareerStats = careerStats(results)
+	return profile, nil
+}
+
+// sortedSeasonNames season names in alphabetical order
+func (s *RaceData) sortedSeasonNames() []string {
+	names := make([]string, 0, len(s.Seasons))
+	for name := range s.Seasons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// careerResults results of all races in all seasons of the lines
+// matching the driver or team
+func (s *RaceData) careerResults(match func(ResultLine) bool) ([]DriverResult, error) {
+	results := []DriverResult{}
+	for _, seasonName := range s.sortedSeasonNames() {
+		for _, race := range s.Seasons[seasonName].Races {
+			if race.RaceResultFile == "" {
+				continue
+			}
+			raceResult, err := s.GetRaceResult(seasonName, race.Name)
+			if err != nil {
+				return nil, err
+			}
+			for _, split := range sortedKeys(raceResult.RaceResultWithPenalty) {
+				results = append(results, splitResults(raceResult, split, s.points, match)...)
+			}
+		}
+	}
+	return results, nil
+}
+
+// splitResults results of the lines of the split matching the driver or team
+func splitResults(raceResult *RaceResult, split string, points PointsRule, match func(ResultLine) bool) []DriverResult {
+	lines := raceResult.RaceResultWithPenalty[split]
+	winnerLaps := 0
+	if len(lines) > 0 {
+		winnerLaps, _ = strconv.Atoi(lines[0].Laps)
+	}
+
+	results := []DriverResult{}
+	for i, line := range lines {
+		if !match(line) {
+			continue
+		}
+		laps, _ := strconv.Atoi(line.Laps)
+		result := DriverResult{
+			Season:     raceResult.SeasonName,
+			SeasonSlug: raceResult.SeasonSlug,
+			Race:       raceResult.RaceName,
+			RaceSlug:   raceResult.RaceSlug,
+			Split:      split,
+			Driver:     line.Driver,
+			DriverID:   line.DriverID,
+			Team:       line.Team,
+			TeamID:     line.TeamID,
+			StartPos:   line.StartPos,
+			Pos:        i + 1,
+			Laps:       line.Laps,
+			Penalty:    line.Penalty,
+			FastestLap: line.FastestLap,
+			DNF:        laps*2 < winnerLaps || laps == 0,
+		}
+		if laps > 0 {
+			result.Points = points.resultPoints(line, i)
+		}
+		if pole, found := raceResult.Pole[split]; found && pole.Team == line.Team {
+			result.Pole = true
+			result.Points += points.Pole
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// careerStats sum up the results of a driver or team
+func careerStats(results []DriverResult) CareerStats {
+	p := CareerStats{}
+	finishes, finishSum := 0, 0
+	for _, result := range results {
+		p.Starts++
+		p.Points += result.Points
+		if result.Pole {
+			p.Poles++
+		}
+		if result.FastestLap {
+			p.FastestLaps++
+		}
+		if result.DNF {
+			p.DNFs++
+			continue
+		}
+		if result.Pos == 1 {
+			p.Wins++
+		}
+		if result.Pos <= 3 {
+			p.Podiums++
+		}
+		finishes++
+		finishSum += result.Pos
+	}
+	if finishes > 0 {
+		p.AverageFinish = float64(finishSum) / float64(finishes)
+	}
+	return p
+}
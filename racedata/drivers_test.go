@@ -0,0 +1,175 @@
+package racedata
+
+import (
+	"errors"
+	"testing"
+)
+
+// addCareerRace race with qualy and race result, the qualy lap times
+// decide the pole
+func addCareerRace(t *testing.T, rd *RaceData, seasonName string, raceName string, qualy string, race string) {
+	t.Helper()
+	if _, _, err := rd.GetResultFilenames(seasonName, raceName); err != nil {
+		if err := rd.AddRace(seasonName, raceName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	header := "pos,participant,class,totalTime,bestLapTime,laps\n"
+	if err := rd.AddResults(seasonName, raceName, []byte(header+qualy), []byte(header+race)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newCareerRaceData two seasons with three races, ben slow is on pole
+// everywhere, anna fast wins the first race and retires in the second
+// and loses the win of the third race by a penalty. The second season
+// lists anna as "A. Fast" who is merged later
+func newCareerRaceData(t *testing.T) *RaceData {
+	t.Helper()
+	rd := newTestRaceData(t)
+	rd.SetPoints(PointsRule{Pole: 3, FastestLap: 1})
+
+	qualy := "1,Team B,GT3,,99000,5\n2,Team A,GT3,,100000,5\n"
+	addCareerRace(t, rd, "Season 1", "Race 1", qualy,
+		"1,Team A,GT3,3600000,100000,30\n2,Team B,GT3,3610000,101000,30\n")
+	addCareerRace(t, rd, "Season 1", "Race 2", qualy,
+		"1,Team B,GT3,3600000,100000,30\n2,Team A,GT3,,101000,10\n")
+
+	if err := rd.AddSeason("Season 2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.AddEntryList("Season 2", "", []byte("driver,team,class\nA. Fast,Team A,GT3\nBen Slow,Team B,GT3\n")); err != nil {
+		t.Fatal(err)
+	}
+	addCareerRace(t, rd, "Season 2", "Race 1", qualy,
+		"1,Team A,GT3,3600000,101000,30\n2,Team B,GT3,3610000,100000,30\n")
+	if err := rd.AddPenalty("Season 2", "Race 1", SESSION_RACE, "20", "1"); err != nil {
+		t.Fatal(err)
+	}
+	return rd
+}
+
+func TestDriverProfile(t *testing.T) {
+	rd := newCareerRaceData(t)
+
+	anna, err := rd.GetDriverProfile("anna-fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the results of "A. Fast" belong to another driver until merged
+	if len(anna.Results) != 2 {
+		t.Fatalf("%v results before the merge, want 2: %+v", len(anna.Results), anna.Results)
+	}
+
+	if err := rd.MergeDrivers("a-fast", "anna-fast"); err != nil {
+		t.Fatal(err)
+	}
+	anna, err = rd.GetDriverProfile("a-fast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anna.ID != "anna-fast" {
+		t.Errorf("profile of the merged duplicate is %v, want anna-fast", anna.ID)
+	}
+
+	want := []DriverResult{
+		{Season: "Season 1", Race: "Race 1", Split: "GT3", Driver: "Anna Fast", Team: "Team A", Pos: 1, Laps: "30", Penalty: "0", Points: 26, FastestLap: true},
+		{Season: "Season 1", Race: "Race 2", Split: "GT3", Driver: "Anna Fast", Team: "Team A", Pos: 2, Laps: "10", Penalty: "0", Points: 18, DNF: true},
+		{Season: "Season 2", Race: "Race 1", Split: "GT3", Driver: "A. Fast", Team: "Team A", Pos: 2, Laps: "30", Penalty: "20", Points: 18},
+	}
+	if len(anna.Results) != len(want) {
+		t.Fatalf("%v results, want %v: %+v", len(anna.Results), len(want), anna.Results)
+	}
+	for i, w := range want {
+		got := anna.Results[i]
+		w.SeasonSlug, w.RaceSlug, w.DriverID, w.TeamID, w.StartPos = got.SeasonSlug, got.RaceSlug, got.DriverID, got.TeamID, got.StartPos
+		if got != w {
+			t.Errorf("result %v\n got %+v\nwant %+v", i, got, w)
+		}
+		if got.DriverID != "anna-fast" || got.TeamID != "team-a" {
+			t.Errorf("result %v linked to driver %v team %v", i, got.DriverID, got.TeamID)
+		}
+	}
+
+	wantStats := CareerStats{Starts: 3, Wins: 1, Podiums: 2, FastestLaps: 1, DNFs: 1, Points: 62, AverageFinish: 1.5}
+	if anna.CareerStats != wantStats {
+		t.Errorf("career\n got %+v\nwant %+v", anna.CareerStats, wantStats)
+	}
+
+	ben, err := rd.GetDriverProfile("ben-slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantStats = CareerStats{Starts: 3, Wins: 2, Podiums: 3, Poles: 3, FastestLaps: 2, Points: 18 + 25 + 25 + 3*3 + 2, AverageFinish: 4.0 / 3}
+	if ben.CareerStats != wantStats {
+		t.Errorf("career\n got %+v\nwant %+v", ben.CareerStats, wantStats)
+	}
+
+	if _, err := rd.GetDriverProfile("carl-late"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown driver: error %v, want %v", err, ErrNotFound)
+	}
+}
+
+// TestCareerPointsMatchStandings the points of the race results add up to
+// the championship points of the season
+func TestCareerPointsMatchStandings(t *testing.T) {
+	rd := newCareerRaceData(t)
+
+	for _, seasonName := range []string{"Season 1", "Season 2"} {
+		standings, err := rd.GetStandings(seasonName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(standings["GT3"]) != 2 {
+			t.Fatalf("%v: unexpected standings %+v", seasonName, standings)
+		}
+		for _, standing := range standings["GT3"] {
+			results, err := rd.careerResults(func(line ResultLine) bool {
+				return line.Team == standing.Team
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			points := 0
+			for _, result := range results {
+				if result.Season == seasonName {
+					points += result.Points
+				}
+			}
+			if points != standing.Points {
+				t.Errorf("%v %v: %v points in the results, %v in the standings", seasonName, standing.Team, points, standing.Points)
+			}
+		}
+	}
+}
+
+func TestTeamCareer(t *testing.T) {
+	rd := newCareerRaceData(t)
+
+	profile, err := rd.GetTeamProfile("team-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profile.Results) != 3 || profile.Wins != 2 || profile.Poles != 3 || len(profile.Seasons) != 2 {
+		t.Errorf("unexpected team career %+v", profile)
+	}
+	if profile.LeaderSplits != 2 {
+		t.Errorf("team b leads %v splits, want both seasons", profile.LeaderSplits)
+	}
+}
+
+func TestCareerStats(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		results []DriverResult
+		want    CareerStats
+	}{
+		{"no results", []DriverResult{}, CareerStats{}},
+		{"only dnfs", []DriverResult{{Pos: 1, DNF: true}, {Pos: 3, DNF: true, Points: 15}}, CareerStats{Starts: 2, DNFs: 2, Points: 15}},
+		{"podiums", []DriverResult{{Pos: 3}, {Pos: 4}, {Pos: 2, Pole: true}}, CareerStats{Starts: 3, Podiums: 2, Poles: 1, AverageFinish: 3}},
+	} {
+		if got := careerStats(tc.results); got != tc.want {
+			t.Errorf("%v:\n got %+v\nwant %+v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package racedata
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MAX_FETCH_SIZE max size of a fetched result file
+const MAX_FETCH_SIZE = 10 * 1024 * 1024 // 10MB
+
+// Fetcher download qualy and race result csv files of an event from
+// a simracing.gp style http endpoint. The url template contains the
+// placeholders {event} and {session}, e.g.
+// https://results.example/events/{event}/{session}.csv
+type Fetcher struct {
+	URLTemplate  string
+	QualySession string
+	RaceSession  string
+	Retries      int
+	RetryDelay   time.Duration
+	Client       *http.Client
+	Logger       *slog.Logger
+}
+
+// NewFetcher new fetcher with timeout per request and number of retries
+// for network errors and server side failures
+func NewFetcher(urlTemplate string, qualySession string, raceSession string, timeout time.Duration, retries int) *Fetcher {
+	return &Fetcher{
+		URLTemplate:  urlTemplate,
+		QualySession: qualySession,
+		RaceSession:  raceSession,
+		Retries:      retries,
+		RetryDelay:   time.Second,
+		Client:       &http.Client{Timeout: timeout},
+		Logger:       slog.Default().With("component", "fetcher"),
+	}
+}
+
+// Fetch download qualy and race result of the event
+func (f *Fetcher) Fetch(eventID string) ([]byte, []byte, error) {
+	if eventID == "" {
+		return nil, nil, fmt.Errorf("no event id")
+	}
+
+	qualyResult, err := f.fetchSession(eventID, f.QualySession)
+	if err != nil {
+		return nil, nil, fmt.Errorf("qualy result - %v", err)
+	}
+
+	raceResult, err := f.fetchSession(eventID, f.RaceSession)
+	if err != nil {
+		return nil, nil, fmt.Errorf("race result - %v", err)
+	}
+
+	return qualyResult, raceResult, nil
+}
+
+// FetchResults download the event results and add them to the race,
+// race data is only locked while the results are added
+func (f *Fetcher) FetchResults(raceData *RaceData, seasonName string, raceName string, eventID string) error {
+	qualyResult, raceResult, err := f.Fetch(eventID)
+	if err != nil {
+		return err
+	}
+
+	raceData.Lock()
+	defer raceData.Unlock()
+	return raceData.AddResults(seasonName, raceName, qualyResult, raceResult)
+}
+
+func (f *Fetcher) sessionURL(eventID string, session string) string {
+	return strings.NewReplacer(
+		"{event}", url.PathEscape(eventID),
+		"{session}", url.PathEscape(session),
+	).Replace(f.URLTemplate)
+}
+
+func (f *Fetcher) fetchSession(eventID string, session string) ([]byte, error) {
+	sessionURL := f.sessionURL(eventID, session)
+
+	var err error
+	for attempt := 0; attempt <= f.Retries; attempt++ {
+		if attempt > 0 {
+			f.Logger.Warn("fetch failed", "url", sessionURL, "error", err, "retry", attempt, "retries", f.Retries)
+			time.Sleep(f.RetryDelay * time.Duration(attempt))
+		}
+
+		var b []byte
+		var retry bool
+		b, retry, err = f.get(sessionURL)
+		if err == nil {
+			f.Logger.Info("fetched", "url", sessionURL, "bytes", len(b))
+			return b, nil
+		}
+		if !retry {
+			break
+		}
+	}
+	return nil, err
+}
+
+// get single request, retry is true for errors which may be temporary
+func (f *Fetcher) get(sessionURL string) ([]byte, bool, error) {
+	resp, err := f.Client.Get(sessionURL)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retry, fmt.Errorf("%v returned %v", sessionURL, resp.Status)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(resp.Body, MAX_FETCH_SIZE))
+	if err != nil {
+		return nil, true, err
+	}
+	return b, false, nil
+}
@@ -0,0 +1,160 @@
+package racedata
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testFetchResult = "pos,participant,class,totalTime,laps\n1,Team A,GT3,3600000,30\n"
+
+// newTestFetcher fetcher of the test server with a short retry delay
+func newTestFetcher(server *httptest.Server, timeout time.Duration, retries int) *Fetcher {
+	f := NewFetcher(server.URL+"/events/{event}/{session}.csv", "qualy", "race", timeout, retries)
+	f.RetryDelay = time.Millisecond
+	f.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	return f
+}
+
+func TestFetch(t *testing.T) {
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		io.WriteString(w, testFetchResult)
+	}))
+	defer server.Close()
+
+	qualy, race, err := newTestFetcher(server, time.Second, 0).Fetch("spa 24h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(qualy) != testFetchResult || string(race) != testFetchResult {
+		t.Errorf("unexpected results %q %q", qualy, race)
+	}
+	if strings.Join(paths, " ") != "/events/spa 24h/qualy.csv /events/spa 24h/race.csv" {
+		t.Errorf("unexpected requests %v", paths)
+	}
+}
+
+func TestFetchRetry(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusTooManyRequests} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// every session fails once
+				if requests.Add(1)%2 == 1 {
+					http.Error(w, "try again", status)
+					return
+				}
+				io.WriteString(w, testFetchResult)
+			}))
+			defer server.Close()
+
+			if _, _, err := newTestFetcher(server, time.Second, 2).Fetch("1"); err != nil {
+				t.Fatal(err)
+			}
+			if n := requests.Load(); n != 4 {
+				t.Errorf("%v requests, want 4", n)
+			}
+		})
+	}
+}
+
+func TestFetchRetriesExhausted(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if _, _, err := newTestFetcher(server, time.Second, 2).Fetch("1"); err == nil {
+		t.Fatal("fetch of an unavailable endpoint succeeded")
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("%v requests, want 3", n)
+	}
+}
+
+func TestFetchNoRetryOnNotFound(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	_, _, err := newTestFetcher(server, time.Second, 3).Fetch("1")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("want 404 error, got %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("%v requests, want 1", n)
+	}
+}
+
+func TestFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	start := time.Now()
+	_, _, err := newTestFetcher(server, 50*time.Millisecond, 1).Fetch("1")
+	if err == nil {
+		t.Fatal("fetch of a hanging endpoint succeeded")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("fetch took %v, timeout was not applied", d)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("%v requests, want 2 with one retry after the timeout", n)
+	}
+}
+
+func TestFetchResults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, testFetchResult)
+	}))
+	defer server.Close()
+
+	rd := newTestRaceData(t)
+	if err := newTestFetcher(server, time.Second, 0).FetchResults(rd, "Season 1", "Race 1", "1"); err != nil {
+		t.Fatal(err)
+	}
+	rr, err := rd.GetRaceResult("Season 1", "Race 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rr.RaceResult["GT3"]) != 1 {
+		t.Errorf("unexpected race result %+v", rr.RaceResult)
+	}
+}
+
+func TestFetchResultsRaceRemoved(t *testing.T) {
+	rd := newTestRaceData(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the race is deleted while the results are downloaded
+		rd.RemoveRace("Season 1", "Race 1")
+		io.WriteString(w, testFetchResult)
+	}))
+	defer server.Close()
+
+	err := newTestFetcher(server, time.Second, 0).FetchResults(rd, "Season 1", "Race 1", "1")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("error %v, want %v", err, ErrNotFound)
+	}
+}
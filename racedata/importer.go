@@ -0,0 +1,115 @@
+package racedata
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// SGP_FORMAT name of the simracing.gp csv result format
+const SGP_FORMAT = "sgp"
+
+// DEFAULT_CLASS class name used for result formats without car classes
+const DEFAULT_CLASS = "Overall"
+
+// ResultImporter converts a session result file of some simulation
+// into the csv result structure used by toRaceResult
+type ResultImporter interface {
+	// Name short format name used in forms and configuration
+	Name() string
+	// Import parse the raw result file
+	Import(data []byte) (*CSVResult, error)
+}
+
+var importers = map[string]ResultImporter{}
+
+func init() {
+	RegisterImporter(sgpImporter{})
+	RegisterImporter(acImporter{})
+	RegisterImporter(rf2Importer{})
+}
+
+// RegisterImporter make a result importer available by its name
+func RegisterImporter(importer ResultImporter) {
+	importers[importer.Name()] = importer
+}
+
+// GetImporter importer registered for format
+func GetImporter(format string) (ResultImporter, error) {
+	importer, found := importers[format]
+	if !found {
+		return nil, fmt.Errorf("result format %v is not supported", format)
+	}
+	return importer, nil
+}
+
+// ImporterNames sorted names of all registered importers
+func ImporterNames() []string {
+	names := []string{}
+	for name := range importers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sgpImporter simracing.gp csv results, the native format
+type sgpImporter struct{}
+
+func (sgpImporter) Name() string {
+	return SGP_FORMAT
+}
+
+func (sgpImporter) Import(data []byte) (*CSVResult, error) {
+	return parseResult(data, nil)
+}
+
+// encodeResult write imported results as csv with simracing.gp column names,
+// penalties of re-imported exports are kept
+func encodeResult(result *CSVResult) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+
+	header := []string{"pos", "startPos", "participant", "car", "class",
+		"totalTime", "bestLapTime", "bestCleanLapTime", "laps", "carNumber", "driver", "penalty"}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, line := range *result {
+		record := []string{
+			strconv.Itoa(int(line.Pos)),
+			line.StartPos,
+			line.Participant,
+			line.Car,
+			line.Class,
+			line.TotalTime,
+			line.BestLapTime,
+			line.BestCleanLapTime,
+			line.Laps,
+			line.CarNumber,
+			line.Driver,
+			line.Penalty,
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// secondsToMilliseconds convert a lap or session time in seconds
+// to the millisecond string used in simracing.gp results
+func secondsToMilliseconds(seconds float64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	return strconv.FormatInt(int64(seconds*1000+0.5), 10)
+}
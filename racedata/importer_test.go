@@ -0,0 +1,102 @@
+package racedata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func importFixture(t *testing.T, format string, fixture string) CSVResult {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	importer, err := GetImporter(format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := importer.Import(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *result
+}
+
+func checkResultLines(t *testing.T, got CSVResult, want CSVResult) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v lines, want %v: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %v\n got %+v\nwant %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestACImporter(t *testing.T) {
+	got := importFixture(t, AC_FORMAT, "ac_race.json")
+
+	// the empty slot is skipped without a gap in the positions,
+	// carl late has no grid position
+	checkResultLines(t, got, CSVResult{
+		{Pos: 1, StartPos: "2", Participant: "Team A", Driver: "Anna Fast", Car: "ks_porsche_911_gt3_r_2016", Class: DEFAULT_CLASS,
+			TotalTime: "324500", BestLapTime: "107100", BestCleanLapTime: "108400", Laps: "3"},
+		{Pos: 2, StartPos: "1", Participant: "Ben Slow", Driver: "Ben Slow", Car: "ks_ferrari_488_gt3", Class: DEFAULT_CLASS,
+			TotalTime: "329800", BestLapTime: "108200", BestCleanLapTime: "108200", Laps: "3"},
+		{Pos: 3, Participant: "Team C", Driver: "Carl Late", Car: "ks_audi_r8_lms", Class: DEFAULT_CLASS,
+			TotalTime: "", BestLapTime: "110000", BestCleanLapTime: "", Laps: "1"},
+	})
+}
+
+func TestRF2Importer(t *testing.T) {
+	got := importFixture(t, RF2_FORMAT, "rf2_race.xml")
+
+	checkResultLines(t, got, CSVResult{
+		{Pos: 1, StartPos: "2", Participant: "Porsche Penske Motorsport", Driver: "Anna Fast", CarNumber: "6",
+			Car: "Porsche 963 #6", Class: "Hypercar", TotalTime: "638900", BestLapTime: "211100", BestCleanLapTime: "212400", Laps: "3"},
+		{Pos: 2, StartPos: "1", Participant: "Ferrari AF Corse", Driver: "Ben Slow", CarNumber: "50",
+			Car: "Ferrari 499P #50", Class: "Hypercar", TotalTime: "641100", BestLapTime: "212830", BestCleanLapTime: "212830", Laps: "3"},
+		{Pos: 3, StartPos: "3", Participant: "Carl Late", Driver: "Carl Late", CarNumber: "27",
+			Car: "Aston Martin Vantage AMR", Class: DEFAULT_CLASS, TotalTime: "", BestLapTime: "230010", BestCleanLapTime: "230010", Laps: "1"},
+	})
+}
+
+func TestImportResultsFixtures(t *testing.T) {
+	for _, tc := range []struct {
+		format  string
+		fixture string
+	}{
+		{AC_FORMAT, "ac_race.json"},
+		{RF2_FORMAT, "rf2_race.xml"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tc.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report := ValidateResults(tc.format, data, data); !report.Ok {
+				t.Fatalf("fixture not valid: %v", report.Error())
+			}
+		})
+	}
+}
+
+func TestEncodeResultKeepsPenalty(t *testing.T) {
+	result := CSVResult{
+		{Pos: 1, StartPos: "2", Participant: "Team A", Driver: "Anna Fast", Class: "GT3",
+			TotalTime: "3600000", BestLapTime: "100000", Laps: "30", Penalty: "5"},
+		{Pos: 2, StartPos: "1", Participant: "Team B", Driver: "Ben Slow", Class: "GT3",
+			TotalTime: "3610000", BestLapTime: "101000", Laps: "30"},
+	}
+	data, err := encodeResult(&result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := sgpImporter{}.Import(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkResultLines(t, *got, result)
+}
@@ -0,0 +1,279 @@
+package racedata
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// INBOX_PROCESSED and INBOX_FAILED folders in the inbox directory
+// the imported result files are moved to
+const INBOX_PROCESSED = "processed"
+const INBOX_FAILED = "failed"
+
+// MAX_INBOX_EVENTS number of import events kept for the index page
+const MAX_INBOX_EVENTS = 50
+
+// INBOX_SETTLE_TIME files younger than this may still be written
+const INBOX_SETTLE_TIME = 5 * time.Second
+
+// InboxEvent result of one automatic import
+type InboxEvent struct {
+	Time    time.Time
+	Season  string
+	Race    string
+	Files   string
+	Ok      bool
+	Message string
+}
+
+// Inbox watch folder for result files, polled in an interval. Results are
+// expected as <season>/<race>/qualy.<ext> and <season>/<race>/race.<ext>,
+// the result format is taken from the file extension
+type Inbox struct {
+	Dir      string
+	Interval time.Duration
+	// SettleTime min age of result files before they are imported
+	SettleTime time.Duration
+
+	raceData *RaceData
+	logger   *slog.Logger
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+
+	mu     sync.Mutex
+	events []InboxEvent
+}
+
+// NewInbox new inbox importing into race data
+func NewInbox(dir string, interval time.Duration, raceData *RaceData) *Inbox {
+	return &Inbox{
+		Dir:        dir,
+		Interval:   interval,
+		SettleTime: INBOX_SETTLE_TIME,
+		raceData:   raceData,
+		logger:     raceData.logger.With("component", "inbox"),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Start poll the inbox directory until Stop is called
+func (i *Inbox) Start() {
+	if err := os.MkdirAll(i.Dir, 0770); err != nil {
+		i.logger.Error("can not create inbox", "dir", i.Dir, "error", err)
+		return
+	}
+	i.logger.Info("watch inbox", "dir", i.Dir, "interval", i.Interval)
+
+	i.done = make(chan struct{})
+	go func() {
+		defer close(i.done)
+		ticker := time.NewTicker(i.Interval)
+		defer ticker.Stop()
+		for {
+			i.Scan()
+			select {
+			case <-ticker.C:
+			case <-i.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stop polling the inbox and wait for a running scan, calling
+// it again is a no-op
+func (i *Inbox) Stop() {
+	i.stopOnce.Do(func() { close(i.stop) })
+	if i.done != nil {
+		<-i.done
+	}
+}
+
+// Events recent import events, newest first
+func (i *Inbox) Events() []InboxEvent {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	events := make([]InboxEvent, len(i.events))
+	for j, e := range i.events {
+		events[len(i.events)-1-j] = e
+	}
+	return events
+}
+
+func (i *Inbox) addEvent(e InboxEvent) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.events = append(i.events, e)
+	if len(i.events) > MAX_INBOX_EVENTS {
+		i.events = i.events[len(i.events)-MAX_INBOX_EVENTS:]
+	}
+}
+
+// Scan import all complete race folders of the inbox once
+func (i *Inbox) Scan() {
+	seasonDirs, err := os.ReadDir(i.Dir)
+	if err != nil {
+		i.logger.Error("can not read inbox", "dir", i.Dir, "error", err)
+		return
+	}
+
+	for _, seasonDir := range seasonDirs {
+		if !seasonDir.IsDir() || seasonDir.Name() == INBOX_PROCESSED || seasonDir.Name() == INBOX_FAILED {
+			continue
+		}
+		raceDirs, err := os.ReadDir(path.Join(i.Dir, seasonDir.Name()))
+		if err != nil {
+			i.logger.Error("can not read inbox season", "season", seasonDir.Name(), "error", err)
+			continue
+		}
+		for _, raceDir := range raceDirs {
+			if raceDir.IsDir() {
+				i.importRace(seasonDir.Name(), raceDir.Name())
+			}
+		}
+	}
+}
+
+func (i *Inbox) importRace(seasonDir string, raceDir string) {
+	dir := path.Join(i.Dir, seasonDir, raceDir)
+	qualyFile, raceFile, ready := inboxResultFiles(dir, i.SettleTime)
+	if !ready {
+		return
+	}
+
+	event := InboxEvent{
+		Time:  time.Now(),
+		Race:  raceDir,
+		Files: path.Join(seasonDir, raceDir, qualyFile) + ", " + path.Join(seasonDir, raceDir, raceFile),
+	}
+
+	err := i.importFiles(seasonDir, raceDir, path.Join(dir, qualyFile), path.Join(dir, raceFile), &event)
+
+	target := INBOX_PROCESSED
+	event.Ok = err == nil
+	event.Message = "imported"
+	if err != nil {
+		target = INBOX_FAILED
+		event.Message = err.Error()
+	}
+	i.logger.Info("inbox import", "season", event.Season, "race", event.Race, "files", event.Files, "ok", event.Ok, "message", event.Message)
+
+	for _, file := range []string{qualyFile, raceFile} {
+		if err := moveInboxFile(i.Dir, target, seasonDir, raceDir, file); err != nil {
+			i.logger.Error("can not move inbox file", "file", file, "error", err)
+		}
+	}
+	os.Remove(dir)
+
+	i.addEvent(event)
+}
+
+func (i *Inbox) importFiles(seasonDir string, raceDir string, qualyPath string, racePath string, event *InboxEvent) error {
+	qualyResult, err := os.ReadFile(qualyPath)
+	if err != nil {
+		return err
+	}
+	raceResult, err := os.ReadFile(racePath)
+	if err != nil {
+		return err
+	}
+
+	i.raceData.Lock()
+	defer i.raceData.Unlock()
+
+	seasonName, found := i.raceData.findSeason(seasonDir)
+	if !found {
+		return fmt.Errorf("season %v not found", seasonDir)
+	}
+	event.Season = seasonName
+
+	raceName, found := i.raceData.findRace(seasonName, raceDir)
+	if !found {
+		raceName = raceDir
+		if err := i.raceData.AddRace(seasonName, raceName); err != nil {
+			return err
+		}
+	}
+	event.Race = raceName
+
+	format := formatFromExtension(racePath)
+	if err := i.raceData.ImportResults(seasonName, raceName, format, qualyResult, raceResult); err != nil {
+		if !found {
+			i.raceData.RemoveRace(seasonName, raceName)
+		}
+		return err
+	}
+	return nil
+}
+
+// inboxResultFiles qualy and race file of a race folder, ready if
+// both exist and have not been modified recently
+func inboxResultFiles(dir string, settleTime time.Duration) (string, string, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", false
+	}
+
+	qualyFile, raceFile := "", ""
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < settleTime {
+			return "", "", false
+		}
+		name := strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		switch {
+		case strings.HasPrefix(name, "qual"):
+			qualyFile = entry.Name()
+		case strings.HasPrefix(name, "race"):
+			raceFile = entry.Name()
+		}
+	}
+	return qualyFile, raceFile, qualyFile != "" && raceFile != ""
+}
+
+func moveInboxFile(inbox string, target string, seasonDir string, raceDir string, file string) error {
+	targetDir := path.Join(inbox, target, seasonDir, raceDir)
+	if err := os.MkdirAll(targetDir, 0770); err != nil {
+		return err
+	}
+	targetFile := time.Now().Format("20060102-150405") + "_" + file
+	return os.Rename(path.Join(inbox, seasonDir, raceDir, file), path.Join(targetDir, targetFile))
+}
+
+// findSeason season by name, slug or the directory name used before slugs
+func (s *RaceData) findSeason(name string) (string, bool) {
+	if seasonName, found := s.SeasonName(name); found {
+		return seasonName, true
+	}
+	for seasonName := range s.Seasons {
+		if legacyDir(seasonName) == legacyDir(name) {
+			return seasonName, true
+		}
+	}
+	return "", false
+}
+
+// findRace race by name, slug or the directory name used before slugs
+func (s *RaceData) findRace(seasonName string, name string) (string, bool) {
+	if raceName, found := s.RaceName(seasonName, name); found {
+		return raceName, true
+	}
+	for _, race := range s.Seasons[seasonName].Races {
+		if legacyDir(race.Name) == legacyDir(name) {
+			return race.Name, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,104 @@
+package racedata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testInboxResult = `pos,participant,class,totalTime,bestLapTime,laps
+1,Team A,GT3,3600000,100000,30
+2,Team B,GT3,3610000,101000,30
+`
+
+func writeInboxFile(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+	filename := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(filename), 0770); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// inboxFiles files below dir relative to it
+func inboxFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files := []string{}
+	filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, p)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files
+}
+
+func TestInbox(t *testing.T) {
+	rd := newTestRaceData(t)
+	dir := t.TempDir()
+	inbox := NewInbox(dir, 10*time.Millisecond, rd)
+	inbox.SettleTime = 100 * time.Millisecond
+
+	writeInboxFile(t, dir, "season-1/Race 2/qualy.csv", testInboxResult)
+	writeInboxFile(t, dir, "season-1/Race 2/race.csv", testInboxResult)
+	writeInboxFile(t, dir, "season-1/Race 3/qualy.csv", testInboxResult)
+	writeInboxFile(t, dir, "season-1/Race 3/race.csv", "pos;team\n1;Team A\n")
+	writeInboxFile(t, dir, "Season 9/Race 1/qualy.csv", testInboxResult)
+	writeInboxFile(t, dir, "Season 9/Race 1/race.csv", testInboxResult)
+	writeInboxFile(t, dir, "season-1/Race 4/qualy.csv", testInboxResult)
+
+	// files younger than the settle time are left alone
+	inbox.Scan()
+	if events := inbox.Events(); len(events) != 0 {
+		t.Fatalf("files were imported before the settle time: %+v", events)
+	}
+
+	inbox.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(inbox.Events()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	inbox.Stop()
+	inbox.Stop()
+
+	ok := map[string]bool{}
+	for _, e := range inbox.Events() {
+		ok[e.Race] = e.Ok
+	}
+	if len(ok) != 3 || !ok["Race 2"] || ok["Race 3"] || ok["Race 1"] {
+		t.Errorf("unexpected events %+v", inbox.Events())
+	}
+
+	if _, _, err := rd.GetResultFilenames("Season 1", "Race 2"); err != nil {
+		t.Errorf("race of the inbox was not created: %v", err)
+	}
+	if _, _, err := rd.GetResultFilenames("Season 1", "Race 3"); err == nil {
+		t.Error("race with invalid results was kept")
+	}
+
+	for _, tc := range []struct {
+		dir   string
+		files int
+	}{
+		{filepath.Join(dir, INBOX_PROCESSED, "season-1", "Race 2"), 2},
+		{filepath.Join(dir, INBOX_FAILED, "season-1", "Race 3"), 2},
+		{filepath.Join(dir, INBOX_FAILED, "Season 9", "Race 1"), 2},
+		// incomplete race folders wait for the missing file
+		{filepath.Join(dir, "season-1", "Race 4"), 1},
+		{filepath.Join(dir, "season-1", "Race 2"), 0},
+	} {
+		if files := inboxFiles(t, tc.dir); len(files) != tc.files {
+			t.Errorf("%v: files %v, want %v", tc.dir, files, tc.files)
+		}
+	}
+}
+
+func TestInboxStopWithoutStart(t *testing.T) {
+	inbox := NewInbox(t.TempDir(), time.Second, newTestRaceData(t))
+	inbox.Stop()
+	inbox.Stop()
+}
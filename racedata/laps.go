@@ -0,0 +1,287 @@
+package racedata
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/artyom/csvstruct"
+)
+
+// required columns of lap by lap csv files
+var requiredLapColumns = []string{"participant", "lap", "lapTime"}
+
+// MAX_LAPS highest lap number accepted in lap by lap csv files, every
+// car gets a slot per lap in the analysis
+const MAX_LAPS = 5000
+
+// CSVLapLine one lap of a car from the lap by lap csv of the race,
+// lap times in milliseconds, empty or 0 if unknown
+type CSVLapLine struct {
+	Participant string `csv:"participant"`
+	Lap         int    `csv:"lap"`
+	LapTime     string `csv:"lapTime"`
+}
+
+type CSVLaps []CSVLapLine
+
+// RaceAnalysis lap by lap data of every split for the race analysis
+type RaceAnalysis struct {
+	SeasonName string               `json:"season"`
+	RaceName   string               `json:"race"`
+	SeasonSlug string               `json:"season_slug"`
+	RaceSlug   string               `json:"race_slug"`
+	Splits     map[string]SplitLaps `json:"splits"`
+}
+
+// SplitLaps laps of the cars of a split in finishing order
+type SplitLaps struct {
+	Laps int       `json:"laps"`
+	Cars []CarLaps `json:"cars"`
+}
+
+// CarLaps lap times in milliseconds and the position in the split at the
+// end of every lap, index 0 is the start. Laps without data are 0
+type CarLaps struct {
+	Driver      string `json:"driver"`
+	Team        string `json:"team"`
+	Startnumber string `json:"race_number"`
+	LapTimes    []int  `json:"lap_times"`
+	Positions   []int  `json:"positions"`
+}
+
+func parseLaps(data []byte) (*CSVLaps, error) {
+	records, err := decodeCSV(data, nil, requiredLapColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	scan, err := csvstruct.NewScanner(records[0], &CSVLapLine{})
+	if err != nil {
+		return nil, fmt.Errorf("new scanner for header %v - %v", records[0], err)
+	}
+
+	laps := CSVLaps{}
+	for _, record := range records[1:] {
+		var line CSVLapLine
+		if err := scan(record, &line); err != nil {
+			return nil, fmt.Errorf("can not parse line %v - %v", record, err)
+		}
+		if line.Lap < 1 || line.Lap > MAX_LAPS {
+			return nil, fmt.Errorf("lap %v of %v must be between 1 and %v", line.Lap, line.Participant, MAX_LAPS)
+		}
+		if _, err := lapTime(line.LapTime); err != nil {
+			return nil, fmt.Errorf("lap time %q of %v in lap %v - %v", line.LapTime, line.Participant, line.Lap, err)
+		}
+		laps = append(laps, line)
+	}
+	if len(laps) == 0 {
+		return nil, fmt.Errorf("lap data contains no laps")
+	}
+	return &laps, nil
+}
+
+func readLaps(filename string) (*CSVLaps, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseLaps(data)
+}
+
+// lapTime lap time in milliseconds, 0 if empty
+func lapTime(milliseconds string) (int, error) {
+	if milliseconds == "" {
+		return 0, nil
+	}
+	t, err := strconv.Atoi(milliseconds)
+	if err != nil || t < 0 {
+		return 0, fmt.Errorf("is not a time in milliseconds")
+	}
+	return t, nil
+}
+
+// AddLapData add the lap by lap csv to a race with results, every
+// participant must be part of the race result
+func (s *RaceData) AddLapData(seasonName string, raceName string, laps []byte) error {
+	season, found := s.Seasons[seasonName]
+	if !found {
+		return fmt.Errorf("season %v %w", seasonName, ErrNotFound)
+	}
+
+	idx := -1
+	for i, race := range season.Races {
+		if race.Name == raceName {
+			idx = i
+		}
+	}
+	if idx == -1 {
+		return fmt.Errorf("race %v in season %v %w", raceName, seasonName, ErrNotFound)
+	}
+	race := &season.Races[idx]
+	if race.RaceResultFile == "" {
+		return fmt.Errorf("race %v in season %v has no results", raceName, seasonName)
+	}
+
+	laps, err := normalizeCSV(laps, nil, requiredLapColumns)
+	if err != nil {
+		return fmt.Errorf("lap data - %v", err)
+	}
+	lapLines, err := parseLaps(laps)
+	if err != nil {
+		return fmt.Errorf("lap data - %v", err)
+	}
+
+	if err := s.checkDataFile(race.RaceResultFile); err != nil {
+		return err
+	}
+	raceResult, err := readResult(race.RaceResultFile)
+	if err != nil {
+		return fmt.Errorf("can not read result file %v", race.RaceResultFile)
+	}
+	participants := map[string]bool{}
+	raceLaps := 0
+	for _, line := range *raceResult {
+		participants[line.Participant] = true
+		if laps, err := strconv.Atoi(line.Laps); err == nil && laps > raceLaps {
+			raceLaps = laps
+		}
+	}
+	for _, line := range *lapLines {
+		if !participants[line.Participant] {
+			return fmt.Errorf("lap data - participant %v is not in the race result", line.Participant)
+		}
+		if raceLaps > 0 && line.Lap > raceLaps {
+			return fmt.Errorf("lap data - lap %v of %v is after the last lap %v of the race", line.Lap, line.Participant, raceLaps)
+		}
+	}
+
+	lapsFilename := path.Join(s.raceDir(season, *race), "laps.csv")
+	s.logger.Info("add lap data", "season", seasonName, "race", raceName, "file", lapsFilename, "laps", len(*lapLines))
+	if err := os.WriteFile(lapsFilename, laps, 0644); err != nil {
+		fatal(s.logger, "can not write lap data", err)
+	}
+
+	race.LapDataFile = lapsFilename
+	s.Seasons[seasonName] = season
+	s.writeSeasonsFile()
+	return nil
+}
+
+// GetRaceAnalysis lap times and positions per lap of every car, split by
+// class and ordered by the result with penalties. Positions are ranked by
+// the summed up lap times of the cars which completed the lap
+func (s *RaceData) GetRaceAnalysis(seasonName string, raceName string) (*RaceAnalysis, error) {
+	season, found := s.Seasons[seasonName]
+	if !found {
+		return nil, fmt.Errorf("season %v %w", seasonName, ErrNotFound)
+	}
+	lapDataFile := ""
+	for _, race := range season.Races {
+		if race.Name == raceName {
+			lapDataFile = race.LapDataFile
+		}
+	}
+	if lapDataFile == "" {
+		return nil, fmt.Errorf("lap data of race %v in season %v %w", raceName, seasonName, ErrNotFound)
+	}
+	if err := s.checkDataFile(lapDataFile); err != nil {
+		return nil, err
+	}
+
+	raceResult, err := s.GetRaceResult(seasonName, raceName)
+	if err != nil {
+		return nil, err
+	}
+	laps, err := readLaps(lapDataFile)
+	if err != nil {
+		return nil, fmt.Errorf("can not read lap data %v", lapDataFile)
+	}
+
+	analysis := &RaceAnalysis{
+		SeasonName: seasonName,
+		RaceName:   raceName,
+		SeasonSlug: raceResult.SeasonSlug,
+		RaceSlug:   raceResult.RaceSlug,
+		Splits:     map[string]SplitLaps{},
+	}
+	for split, lines := range raceResult.RaceResultWithPenalty {
+		analysis.Splits[split] = splitLaps(lines, laps)
+	}
+	return analysis, nil
+}
+
+// splitLaps lap times and positions of the cars of the result lines
+func splitLaps(lines ResultLines, laps *CSVLaps) SplitLaps {
+	split := SplitLaps{}
+	cars := map[string]int{}
+	for i, line := range lines {
+		cars[line.Team] = i
+		split.Cars = append(split.Cars, CarLaps{
+			Driver:      line.Driver,
+			Team:        line.Team,
+			Startnumber: line.Startnumber,
+		})
+	}
+
+	for _, line := range *laps {
+		if _, found := cars[line.Participant]; found && line.Lap > split.Laps {
+			split.Laps = line.Lap
+		}
+	}
+	for i := range split.Cars {
+		split.Cars[i].LapTimes = make([]int, split.Laps+1)
+		split.Cars[i].Positions = make([]int, split.Laps+1)
+	}
+	for _, line := range *laps {
+		if i, found := cars[line.Participant]; found {
+			split.Cars[i].LapTimes[line.Lap], _ = lapTime(line.LapTime)
+		}
+	}
+
+	// start positions ranked by the start position of the result
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		pa, _ := strconv.Atoi(lines[order[a]].StartPos)
+		pb, _ := strconv.Atoi(lines[order[b]].StartPos)
+		return pa < pb
+	})
+	for pos, i := range order {
+		split.Cars[i].Positions[0] = pos + 1
+	}
+
+	elapsed := make([]int, len(split.Cars))
+	completed := make([]bool, len(split.Cars))
+	for lap := 1; lap <= split.Laps; lap++ {
+		running := []int{}
+		for i, car := range split.Cars {
+			completed[i] = car.LapTimes[lap] > 0 && (lap == 1 || completed[i])
+			if completed[i] {
+				elapsed[i] += car.LapTimes[lap]
+				running = append(running, i)
+			}
+		}
+		sort.SliceStable(running, func(a, b int) bool {
+			return elapsed[running[a]] < elapsed[running[b]]
+		})
+		for pos, i := range running {
+			split.Cars[i].Positions[lap] = pos + 1
+		}
+	}
+	return split
+}
+
+// HasLapData the race has lap by lap data
+func (s *RaceData) HasLapData(seasonName string, raceName string) bool {
+	for _, race := range s.Seasons[seasonName].Races {
+		if race.Name == raceName {
+			return race.LapDataFile != ""
+		}
+	}
+	return false
+}
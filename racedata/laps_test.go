@@ -0,0 +1,58 @@
+package racedata
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddLapData(t *testing.T) {
+	rd := newTestRaceData(t)
+	result := []byte("pos,startPos,participant,class,totalTime,laps\n1,2,Team A,GT3,200000,2\n2,1,Team B,GT3,203000,2\n")
+	if err := rd.AddResults("Season 1", "Race 1", result, result); err != nil {
+		t.Fatal(err)
+	}
+
+	laps := "participant,lap,lapTime\nTeam A,1,101000\nTeam B,1,100000\nTeam A,2,99000\nTeam B,2,103000\n"
+	if err := rd.AddLapData("Season 1", "Race 1", []byte(laps)); err != nil {
+		t.Fatal(err)
+	}
+
+	analysis, err := rd.GetRaceAnalysis("Season 1", "Race 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	split := analysis.Splits["GT3"]
+	if split.Laps != 2 || len(split.Cars) != 2 {
+		t.Fatalf("unexpected split %+v", split)
+	}
+	// Team A starts second, is behind after lap 1 and leads after lap 2
+	if got := split.Cars[0].Positions; len(got) != 3 || got[0] != 2 || got[1] != 2 || got[2] != 1 {
+		t.Errorf("unexpected positions of Team A %v", got)
+	}
+}
+
+func TestAddLapDataLapRange(t *testing.T) {
+	rd := newTestRaceData(t)
+	result := []byte("pos,participant,class,totalTime,laps\n1,Team A,GT3,200000,2\n")
+	if err := rd.AddResults("Season 1", "Race 1", result, result); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		laps string
+		want string
+	}{
+		{"participant,lap,lapTime\nTeam A,3,100000\n", "after the last lap 2"},
+		{"participant,lap,lapTime\nTeam A,100000000,100000\n", "must be between 1 and"},
+		{"participant,lap,lapTime\nTeam A,0,100000\n", "must be between 1 and"},
+		{"participant,lap,lapTime\nTeam X,1,100000\n", "is not in the race result"},
+	} {
+		err := rd.AddLapData("Season 1", "Race 1", []byte(tc.laps))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: got error %v, want %q", tc.laps, err, tc.want)
+		}
+	}
+	if rd.HasLapData("Season 1", "Race 1") {
+		t.Error("invalid lap data was stored")
+	}
+}
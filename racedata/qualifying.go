@@ -0,0 +1,90 @@
+package racedata
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// qualifyingResult qualifying classification per split sorted by best lap,
+// penalty seconds are added to the lap time, cars without a lap time are last
+func qualifyingResult(qr *CSVResult, el *CSVEntryList) map[string]ResultLines {
+	result := map[string]ResultLines{}
+	for _, line := range *qr {
+		resultLine := csvResultToResultLine(line)
+		resultLine.addDriverAndRaceNumber(el)
+		result[line.Class] = append(result[line.Class], resultLine)
+	}
+
+	for _, lines := range result {
+		sort.SliceStable(lines, func(i, j int) bool {
+			ti, oki := qualifyingTime(lines[i])
+			tj, okj := qualifyingTime(lines[j])
+			if oki != okj {
+				return oki
+			}
+			if !oki {
+				return lines[i].Pos < lines[j].Pos
+			}
+			return ti < tj
+		})
+
+		times := make([]int, len(lines))
+		valid := make([]bool, len(lines))
+		for i := range lines {
+			times[i], valid[i] = qualifyingTime(lines[i])
+		}
+		for i := range lines {
+			if valid[i] && i > 0 {
+				lines[i].Gap = formatGap(times[i] - times[0])
+				lines[i].Interval = formatGap(times[i] - times[i-1])
+			}
+			if valid[i] {
+				lines[i].BestLapTime = convertMilliseconds(lines[i].BestLapTime)
+			}
+			if _, err := strconv.Atoi(lines[i].TotalTime); err == nil {
+				lines[i].TotalTime = convertMilliseconds(lines[i].TotalTime)
+			}
+		}
+	}
+	return result
+}
+
+// qualifyingTime best lap in milliseconds with the penalty seconds,
+// false if the car did not set a lap time
+func qualifyingTime(line ResultLine) (int, bool) {
+	t, err := strconv.Atoi(line.BestLapTime)
+	if err != nil || t <= 0 {
+		return 0, false
+	}
+	penalty, _ := strconv.Atoi(line.Penalty)
+	return t + penalty*1000, true
+}
+
+// poles pole position of every split, the first car with a lap time
+func poles(qualifying map[string]ResultLines) map[string]ResultLine {
+	poles := map[string]ResultLine{}
+	for split, lines := range qualifying {
+		if len(lines) > 0 && lines[0].BestLapTime != "" && lines[0].BestLapTime != "0" {
+			poles[split] = lines[0]
+		}
+	}
+	return poles
+}
+
+// formatGap gap in milliseconds as +s.mmm or +m:ss.mmm
+func formatGap(ms int) string {
+	if ms < 60000 {
+		return fmt.Sprintf("+%d.%03d", ms/1000, ms%1000)
+	}
+	return fmt.Sprintf("+%d:%02d.%03d", ms/60000, ms/1000%60, ms%1000)
+}
+
+// GetQualyCSVExport qualifying classification of the split as csv
+func GetQualyCSVExport(raceResult *RaceResult, split string, w io.Writer) {
+	fmt.Fprintf(w, "pos,race number,team,driver,best lap,penalty,gap,interval\n")
+	for i, line := range raceResult.QualiyResult[split] {
+		fmt.Fprintf(w, "%v,%v,%v,%v,%v,%v,%v,%v\n", i+1, line.Startnumber, line.Team, line.Driver, line.BestLapTime, line.Penalty, line.Gap, line.Interval)
+	}
+}
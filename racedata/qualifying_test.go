@@ -0,0 +1,100 @@
+package racedata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQualifyingResult(t *testing.T) {
+	qualy := &CSVResult{
+		{Pos: 1, Participant: "Team A", Class: "GT3", BestLapTime: "100000", Penalty: "0"},
+		{Pos: 2, Participant: "Team B", Class: "GT3", BestLapTime: "100250", Penalty: "0"},
+		{Pos: 3, Participant: "Team C", Class: "GT3", BestLapTime: "99000", Penalty: "3"},
+		{Pos: 4, Participant: "Team D", Class: "GT3", BestLapTime: "", Penalty: "0"},
+		{Pos: 5, Participant: "Team E", Class: "GT3", BestLapTime: "0", Penalty: "0"},
+		{Pos: 6, Participant: "Team F", Class: "GT3", BestLapTime: "161500", Penalty: "0"},
+		{Pos: 1, Participant: "Team G", Class: "GT4", BestLapTime: "110000", Penalty: "0"},
+		{Pos: 2, Participant: "Team H", Class: "GT4", BestLapTime: "109000", Penalty: ""},
+	}
+	el := &CSVEntryList{{Driver: "Anna Fast", Team: "Team A", RaceNumber: "1"}}
+	result := qualifyingResult(qualy, el)
+
+	type row struct {
+		Team, BestLapTime, Gap, Interval string
+	}
+	for split, want := range map[string][]row{
+		"GT3": {
+			{"Team A", "00:01:40.000", "", ""},
+			{"Team B", "00:01:40.250", "+0.250", "+0.250"},
+			{"Team C", "00:01:39.000", "+2.000", "+1.750"},
+			{"Team F", "00:02:41.500", "+1:01.500", "+59.500"},
+			{"Team D", "", "", ""},
+			{"Team E", "0", "", ""},
+		},
+		"GT4": {
+			{"Team H", "00:01:49.000", "", ""},
+			{"Team G", "00:01:50.000", "+1.000", "+1.000"},
+		},
+	} {
+		got := []row{}
+		for _, line := range result[split] {
+			got = append(got, row{line.Team, line.BestLapTime, line.Gap, line.Interval})
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("qualifying of %v\n%+v\nwant\n%+v", split, got, want)
+		}
+	}
+	if line := result["GT3"][0]; line.Driver != "Anna Fast" || line.Startnumber != "1" {
+		t.Errorf("entry list not applied to %+v", line)
+	}
+
+	pole := poles(result)
+	if pole["GT3"].Team != "Team A" || pole["GT4"].Team != "Team H" {
+		t.Errorf("unexpected poles %+v", pole)
+	}
+	if _, found := poles(map[string]ResultLines{"GT3": {{Team: "Team D"}}})["GT3"]; found {
+		t.Error("pole without a lap time")
+	}
+}
+
+func TestAddQualyPenalty(t *testing.T) {
+	rd := newTestRaceData(t)
+	result := []byte(`pos,participant,class,totalTime,bestLapTime,laps
+1,Team A,GT3,3600000,100000,30
+2,Team B,GT3,3610000,101000,30
+`)
+	if err := rd.AddResults("Season 1", "Race 1", result, result); err != nil {
+		t.Fatal(err)
+	}
+	rd.SetPoints(PointsRule{Pole: 3})
+
+	if err := rd.AddPenalty("Season 1", "Race 1", SESSION_QUALY, "2", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.AddPenalty("Season 1", "Race 1", "warmup", "2", "1"); err == nil {
+		t.Error("unknown session accepted")
+	}
+
+	rr, err := rd.GetRaceResult("Season 1", "Race 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr.Pole["GT3"].Team != "Team B" {
+		t.Errorf("pole %v, want Team B after the qualifying penalty", rr.Pole["GT3"].Team)
+	}
+	if penalty := rr.RaceResultWithPenalty["GT3"][0].Penalty; penalty != "0" {
+		t.Errorf("qualifying penalty added to the race result, penalty %v", penalty)
+	}
+
+	standings, err := rd.GetStandings("Season 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := map[string]int{}
+	for _, standing := range standings["GT3"] {
+		points[standing.Team] = standing.Points
+	}
+	if points["Team A"] != 25 || points["Team B"] != 21 {
+		t.Errorf("unexpected points with pole points %v", points)
+	}
+}
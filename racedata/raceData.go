@@ -2,106 +2,241 @@ package racedata
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
+	"sync"
 )
 
+// ErrNotFound season, race or entry not found
+var ErrNotFound = errors.New("not found")
+
+// ErrNotUnique name already used
+var ErrNotUnique = errors.New("is not unique")
+
+// ErrInvalidName name can not be used as directory name
+var ErrInvalidName = errors.New("is not a valid name")
+
+// ErrInvalidValue penalty or session of a penalty can not be used
+var ErrInvalidValue = errors.New("is not valid")
+
+// SESSION_RACE and SESSION_QUALY result of a race penalties are added to
+const SESSION_RACE = "race"
+const SESSION_QUALY = "qualy"
+
 type RaceData struct {
 	DataDir      string    `json:"data_dir"`
 	Seasons      SeasonMap `json:"season"`
 	RaceDataFile string    `json:"racedata_filename"`
+
+	// branding instance branding, stored in BRANDING_FILE
+	branding Branding
+
+	// drivers driver registry, stored in DRIVER_REGISTRY_FILE
+	drivers []RegisteredDriver
+
+	// teams team registry, stored in TEAM_REGISTRY_FILE
+	teams []RegisteredTeam
+
+	// points bonus points of the standings, set by SetPoints
+	points PointsRule
+
+	// mu guards Seasons and the data files, held by the callers
+	// of RaceData operations, e.g. http handlers and the inbox
+	mu sync.RWMutex
+
+	// penaltyCounts penalties of the race result files for GetStats,
+	// guarded by statsMu as GetStats runs under the read lock
+	penaltyCounts map[string]penaltyCount
+	statsMu       sync.Mutex
+
+	logger *slog.Logger
 }
 
 type Season struct {
+	// Slug directory and url name of the season
+	Slug         string `json:"slug"`
 	EntyListFile string `json:"entylist_filename"`
 	Races        []Race
+	// Branding season branding over the instance branding, nil if not set
+	Branding *Branding `json:"branding,omitempty"`
 }
 
 type SeasonMap map[string]Season
 
 type Race struct {
-	Name            string `json:"name"`
+	Name string `json:"name"`
+	// Slug directory and url name of the race, unique in the season
+	Slug            string `json:"slug"`
 	QualyResultFile string `json:"qualy_result_file"`
 	RaceResultFile  string `json:"race_result_file"`
+	// LapDataFile optional lap by lap csv of the race
+	LapDataFile string `json:"lap_data_file,omitempty"`
 }
 
 // NewRaceData new race data with data in dataDir
-// and race data configuration in raceDataFile
-func NewRaceData(dataDir string, raceDataFile string) *RaceData {
+// and race data configuration in raceDataFile,
+// the default logger is used if logger is nil
+func NewRaceData(dataDir string, raceDataFile string, logger *slog.Logger) *RaceData {
+	if logger == nil {
+		logger = slog.Default()
+	}
 
-	newRaceData := RaceData{
+	newRaceData := &RaceData{
 		RaceDataFile: raceDataFile,
 		DataDir:      dataDir,
 		Seasons:      SeasonMap{},
+		logger:       logger,
 	}
 
 	if _, err := os.Stat(raceDataFile); err == nil {
 		newRaceData.readSeasonsFile()
+		newRaceData.migrateSlugs()
 	} else {
 		newRaceData.createSeasonsFile()
 	}
+	newRaceData.readBrandingFile()
+	newRaceData.readDriverRegistry()
+	newRaceData.migrateDriverRegistry()
+	newRaceData.readTeamRegistry()
+	newRaceData.migrateTeamRegistry()
+
+	logger.Info("new race data", "data_dir", newRaceData.DataDir, "data_file", newRaceData.RaceDataFile)
+
+	return newRaceData
+}
 
-	log.Printf("new race data with data dir: %v, data file: %v\n",
-		newRaceData.DataDir, newRaceData.RaceDataFile)
+// Lock lock race data for modifications
+func (s *RaceData) Lock() {
+	s.mu.Lock()
+}
 
-	return &newRaceData
+func (s *RaceData) Unlock() {
+	s.mu.Unlock()
 }
 
-func (s *RaceData) AddEntryList(seasonName string, entryList []byte) error {
+// RLock lock race data for reading
+func (s *RaceData) RLock() {
+	s.mu.RLock()
+}
+
+func (s *RaceData) RUnlock() {
+	s.mu.RUnlock()
+}
+
+// AddEntryList add the entry list csv to the season, the header is mapped
+// with the named csv profile or the default column names if empty
+func (s *RaceData) AddEntryList(seasonName string, profileName string, entryList []byte) error {
 	season, found := s.Seasons[seasonName]
 	if !found {
-		return fmt.Errorf("season %v not found", seasonName)
+		return fmt.Errorf("season %v %w", seasonName, ErrNotFound)
 	}
 
-	entyListFilename := path.Join(s.DataDir, nameToDir(seasonName), "enty_list.csv")
-	log.Printf("add entry list %v to season %v\n", entyListFilename, season)
-
-	if err := os.WriteFile(entyListFilename, entryList, 0644); err != nil {
+	profile, err := GetCSVProfile(profileName)
+	if err != nil {
 		return err
 	}
 
+	entryList, err = normalizeCSV(entryList, profile, requiredEntryListColumns)
+	if err != nil {
+		return fmt.Errorf("entry list - %v", err)
+	}
+
 	// check entry list team name unique
-	if err := checkEntryListUnique(entyListFilename); err != nil {
-		log.Printf("%v\n", err)
+	if err := checkEntryListUnique(entryList); err != nil {
+		s.logger.Warn("invalid entry list", "season", seasonName, "error", err)
 		return err
 	}
 
+	// new drivers are only kept once the entry list is written
+	registered := len(s.drivers)
+	entryList, err = s.linkEntryListDrivers(entryList)
+	if err != nil {
+		s.drivers = s.drivers[:registered]
+		return fmt.Errorf("entry list - %v", err)
+	}
+
+	entyListFilename := path.Join(s.seasonDir(season), "enty_list.csv")
+	s.logger.Info("add entry list", "season", seasonName, "file", entyListFilename)
+
+	if err := os.WriteFile(entyListFilename, entryList, 0644); err != nil {
+		s.drivers = s.drivers[:registered]
+		return err
+	}
+	if len(s.drivers) > registered {
+		s.logger.Info("registered drivers", "drivers", len(s.drivers)-registered)
+		s.writeDriverRegistry()
+	}
+
 	season.EntyListFile = entyListFilename
 	s.Seasons[seasonName] = season
 	s.writeSeasonsFile()
+
+	if entries, err := readEntryList(entyListFilename); err == nil {
+		teams := []string{}
+		for _, line := range *entries {
+			teams = append(teams, line.Team)
+		}
+		s.registerTeams(teams)
+	}
 	return nil
 
 }
 
-func (s *RaceData) AddPenalty(seasonName string, raceName string, penalty string, pos string) error {
+// SetEntryList replace the entry list of the season
+func (s *RaceData) SetEntryList(seasonName string, entryList *EntryList) error {
+	b, err := encodeEntryList(entryList)
+	if err != nil {
+		return err
+	}
+	return s.AddEntryList(seasonName, "", b)
+}
+
+// AddPenalty add penalty seconds to the car at pos in the race result or,
+// with SESSION_QUALY, to its best lap in the qualifying result. An empty
+// session is the race
+func (s *RaceData) AddPenalty(seasonName string, raceName string, session string, penalty string, pos string) error {
 	season, found := s.Seasons[seasonName]
 	if !found {
-		return fmt.Errorf("season %v not found", seasonName)
+		return fmt.Errorf("season %v %w", seasonName, ErrNotFound)
 	}
 
 	for _, race := range season.Races {
 		if race.Name == raceName {
-			raceResultFilename := race.RaceResultFile
-			if err := addPenaltyToResult(raceResultFilename, penalty, pos); err != nil {
-				return fmt.Errorf("can not apply penalty %v to pos %v in season %v and race %v", penalty, pos, seasonName, raceName)
+			var resultFilename string
+			switch session {
+			case "", SESSION_RACE:
+				resultFilename = race.RaceResultFile
+			case SESSION_QUALY:
+				resultFilename = race.QualyResultFile
+			default:
+				return fmt.Errorf("session %q %w, use %v or %v", session, ErrInvalidValue, SESSION_RACE, SESSION_QUALY)
 			}
+			if resultFilename == "" {
+				return fmt.Errorf("results of race %v in season %v %w", raceName, seasonName, ErrNotFound)
+			}
+			if err := addPenaltyToResult(resultFilename, penalty, pos); err != nil {
+				return fmt.Errorf("can not apply penalty %v to pos %v in season %v and race %v - %w", penalty, pos, seasonName, raceName, err)
+			}
+			return nil
 		}
 	}
-	return fmt.Errorf("race %v in season %v not found", raceName, seasonName)
+	return fmt.Errorf("race %v in season %v %w", raceName, seasonName, ErrNotFound)
 }
 
 func (s *RaceData) writeSeasonsFile() {
 	b, err := json.MarshalIndent(s.Seasons, "", "   ")
 	if err != nil {
-		log.Fatal(err)
+		fatal(s.logger, "can not encode race data", err)
 	}
 
 	if err := os.WriteFile(s.RaceDataFile, b, 0644); err != nil {
-		log.Fatal(err)
+		fatal(s.logger, "can not write race data file", err)
 	}
 
 }
@@ -109,7 +244,7 @@ func (s *RaceData) writeSeasonsFile() {
 func (s *RaceData) GetEntryListFilename(seasonName string) (string, error) {
 	season, found := s.Seasons[seasonName]
 	if !found {
-		return "", fmt.Errorf("season %v not found", seasonName)
+		return "", fmt.Errorf("season %v %w", seasonName, ErrNotFound)
 	}
 	return season.EntyListFile, nil
 }
@@ -117,7 +252,7 @@ func (s *RaceData) GetEntryListFilename(seasonName string) (string, error) {
 func (s *RaceData) GetResultFilenames(seasonName string, raceName string) (string, string, error) {
 	season, found := s.Seasons[seasonName]
 	if !found {
-		return "", "", fmt.Errorf("season %v not found", seasonName)
+		return "", "", fmt.Errorf("season %v %w", seasonName, ErrNotFound)
 	}
 
 	for _, race := range season.Races {
@@ -125,55 +260,112 @@ func (s *RaceData) GetResultFilenames(seasonName string, raceName string) (strin
 			return race.QualyResultFile, race.RaceResultFile, nil
 		}
 	}
-	return "", "", fmt.Errorf("no race %v in season %v not found", raceName, seasonName)
+	return "", "", fmt.Errorf("race %v in season %v %w", raceName, seasonName, ErrNotFound)
 }
 
+// AddResults validate qualy and race result and store them with the race,
+// ErrNotFound if the season or race does not exist
 func (s *RaceData) AddResults(seasonName string, raceName string, qualyResult []byte, raceResult []byte) error {
-	log.Printf("add result to season %v race %v", seasonName, raceName)
+	s.logger.Info("add results", "season", seasonName, "race", raceName)
 	season, found := s.Seasons[seasonName]
 	if !found {
-		return fmt.Errorf("season %v not found", seasonName)
+		return fmt.Errorf("season %v %w", seasonName, ErrNotFound)
 	}
-
+	// the race may have been removed while the results were fetched
+	raceIdx := -1
 	for i, race := range season.Races {
 		if race.Name == raceName {
-			qualyCsvFilename := path.Join(s.dataDir(seasonName, raceName), "qualy_result.csv")
-			season.Races[i].QualyResultFile = qualyCsvFilename
-			if err := os.WriteFile(qualyCsvFilename, qualyResult, 0644); err != nil {
-				log.Fatal(err)
-			}
-			if err := addPenaltyColumn(qualyCsvFilename); err != nil {
-				log.Fatal(err)
-			}
+			raceIdx = i
+		}
+	}
+	if raceIdx == -1 {
+		return fmt.Errorf("race %v in season %v %w", raceName, seasonName, ErrNotFound)
+	}
 
-			raceCsvFilename := path.Join(s.dataDir(seasonName, raceName), "race_result.csv")
-			season.Races[i].RaceResultFile = raceCsvFilename
-			if err := os.WriteFile(raceCsvFilename, raceResult, 0644); err != nil {
-				log.Fatal(err)
-			}
-			if err := addPenaltyColumn(raceCsvFilename); err != nil {
-				log.Fatal(err)
-			}
+	qualyResult, err := normalizeCSV(qualyResult, nil, requiredResultColumns)
+	if err != nil {
+		return fmt.Errorf("qualy result - %v", err)
+	}
 
-			if err := areTeamNamesUnique(qualyCsvFilename); err != nil {
-				return err
-			}
+	raceResult, err = normalizeCSV(raceResult, nil, requiredResultColumns)
+	if err != nil {
+		return fmt.Errorf("race result - %v", err)
+	}
 
-			if err := areTeamNamesUnique(raceCsvFilename); err != nil {
-				return err
+	if err := ValidateResults(SGP_FORMAT, qualyResult, raceResult).Error(); err != nil {
+		return err
+	}
+	teams := []string{}
+	for _, data := range [][]byte{qualyResult, raceResult} {
+		if result, err := parseResult(data, nil); err == nil {
+			for _, line := range *result {
+				teams = append(teams, line.Participant)
 			}
 		}
 	}
 
+	race := season.Races[raceIdx]
+	qualyCsvFilename := path.Join(s.raceDir(season, race), "qualy_result.csv")
+	season.Races[raceIdx].QualyResultFile = qualyCsvFilename
+	if err := os.WriteFile(qualyCsvFilename, qualyResult, 0644); err != nil {
+		fatal(s.logger, "can not write qualy result", err)
+	}
+	if err := addPenaltyColumn(qualyCsvFilename); err != nil {
+		fatal(s.logger, "can not add penalty column", err)
+	}
+
+	raceCsvFilename := path.Join(s.raceDir(season, race), "race_result.csv")
+	season.Races[raceIdx].RaceResultFile = raceCsvFilename
+	if err := os.WriteFile(raceCsvFilename, raceResult, 0644); err != nil {
+		fatal(s.logger, "can not write race result", err)
+	}
+	if err := addPenaltyColumn(raceCsvFilename); err != nil {
+		fatal(s.logger, "can not add penalty column", err)
+	}
+
 	s.Seasons[seasonName] = season
 	s.writeSeasonsFile()
+	s.registerTeams(teams)
 	return nil
 }
 
+// ImportResults convert qualy and race result files of the given format
+// to simracing.gp csv results and add them to the race
+func (s *RaceData) ImportResults(seasonName string, raceName string, format string, qualyResult []byte, raceResult []byte) error {
+	if format == "" || format == SGP_FORMAT {
+		return s.AddResults(seasonName, raceName, qualyResult, raceResult)
+	}
+
+	importer, err := GetImporter(format)
+	if err != nil {
+		return err
+	}
+
+	qualyCsv, err := importResult(importer, qualyResult)
+	if err != nil {
+		return fmt.Errorf("qualy result - %v", err)
+	}
+
+	raceCsv, err := importResult(importer, raceResult)
+	if err != nil {
+		return fmt.Errorf("race result - %v", err)
+	}
+
+	return s.AddResults(seasonName, raceName, qualyCsv, raceCsv)
+}
+
+func importResult(importer ResultImporter, data []byte) ([]byte, error) {
+	result, err := importer.Import(data)
+	if err != nil {
+		return nil, err
+	}
+	return encodeResult(result)
+}
+
 func (s *RaceData) RemoveRace(seasonName string, raceName string) error {
 	season, found := s.Seasons[seasonName]
 	if !found {
-		return fmt.Errorf("season %v not found", seasonName)
+		return fmt.Errorf("season %v %w", seasonName, ErrNotFound)
 	}
 
 	deleteIdx := -1
@@ -184,7 +376,7 @@ func (s *RaceData) RemoveRace(seasonName string, raceName string) error {
 	}
 
 	if deleteIdx == -1 {
-		return fmt.Errorf("race %v in season %v not found", raceName, seasonName)
+		return fmt.Errorf("race %v in season %v %w", raceName, seasonName, ErrNotFound)
 	}
 
 	season.Races = append(season.Races[:deleteIdx], season.Races[deleteIdx+1:]...)
@@ -194,55 +386,162 @@ func (s *RaceData) RemoveRace(seasonName string, raceName string) error {
 }
 
 func (s *RaceData) AddRace(seasonName string, raceName string) error {
-	log.Printf("season %v add race %v", seasonName, raceName)
+	s.logger.Info("add race", "season", seasonName, "race", raceName)
 	season, found := s.Seasons[seasonName]
 	if !found {
-		return fmt.Errorf("season %v not found", seasonName)
+		return fmt.Errorf("season %v %w", seasonName, ErrNotFound)
+	}
+	if err := checkName(raceName); err != nil {
+		return err
 	}
 
 	for _, race := range season.Races {
 		if race.Name == raceName {
-			return fmt.Errorf("race name %v is not unique", raceName)
+			return fmt.Errorf("race name %v %w", raceName, ErrNotUnique)
 		}
 	}
 
-	season.Races = append(season.Races, Race{Name: raceName})
+	race := Race{Name: raceName, Slug: s.raceSlug(season, raceName, "")}
+	season.Races = append(season.Races, race)
 	s.Seasons[seasonName] = season
 	s.writeSeasonsFile()
 
-	if err := os.MkdirAll(path.Join(s.DataDir, nameToDir(seasonName), nameToDir(raceName)), 0770); err != nil {
-		log.Fatal(err)
+	if err := os.MkdirAll(s.raceDir(season, race), 0770); err != nil {
+		fatal(s.logger, "can not create race directory", err)
 	}
 
 	return nil
 }
 
 func (s *RaceData) AddSeason(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
 	_, found := s.Seasons[name]
 	if found {
-		return fmt.Errorf("season name %v is not unique", name)
+		return fmt.Errorf("season name %v %w", name, ErrNotUnique)
+	}
+
+	season := Season{Slug: s.seasonSlug(name, ""), Races: []Race{}}
+	s.Seasons[name] = season
+	s.writeSeasonsFile()
+
+	if err := os.MkdirAll(s.seasonDir(season), 0770); err != nil {
+		fatal(s.logger, "can not create season directory", err)
+	}
+
+	return nil
+}
+
+// RemoveSeason remove the season, the data files are kept
+func (s *RaceData) RemoveSeason(name string) error {
+	if _, found := s.Seasons[name]; !found {
+		return fmt.Errorf("season %v %w", name, ErrNotFound)
 	}
 
-	s.Seasons[name] = Season{Races: []Race{}}
+	delete(s.Seasons, name)
 	s.writeSeasonsFile()
+	return nil
+}
+
+// RenameSeason rename the season and move its data directory
+func (s *RaceData) RenameSeason(oldName string, newName string) error {
+	season, found := s.Seasons[oldName]
+	if !found {
+		return fmt.Errorf("season %v %w", oldName, ErrNotFound)
+	}
+	if err := checkName(newName); err != nil {
+		return err
+	}
+	if _, found := s.Seasons[newName]; found {
+		return fmt.Errorf("season name %v %w", newName, ErrNotUnique)
+	}
+
+	oldDir := s.seasonDir(season)
+	season.Slug = s.seasonSlug(newName, season.Slug)
+	if err := s.moveSeasonDir(&season, oldDir); err != nil {
+		return err
+	}
+
+	delete(s.Seasons, oldName)
+	s.Seasons[newName] = season
+	s.writeSeasonsFile()
+	return nil
+}
+
+// RenameRace rename the race and move its data directory
+func (s *RaceData) RenameRace(seasonName string, oldName string, newName string) error {
+	season, found := s.Seasons[seasonName]
+	if !found {
+		return fmt.Errorf("season %v %w", seasonName, ErrNotFound)
+	}
+	if err := checkName(newName); err != nil {
+		return err
+	}
 
-	if err := os.MkdirAll(path.Join(s.DataDir, nameToDir(name)), 0770); err != nil {
-		log.Fatal(err)
+	idx := -1
+	for i, race := range season.Races {
+		if race.Name == newName {
+			return fmt.Errorf("race name %v %w", newName, ErrNotUnique)
+		}
+		if race.Name == oldName {
+			idx = i
+		}
+	}
+	if idx == -1 {
+		return fmt.Errorf("race %v in season %v %w", oldName, seasonName, ErrNotFound)
 	}
 
+	race := &season.Races[idx]
+	oldDir := s.raceDir(season, *race)
+	race.Slug = s.raceSlug(season, newName, race.Slug)
+	newDir := s.raceDir(season, *race)
+	if err := moveDataDir(oldDir, newDir); err != nil {
+		return err
+	}
+
+	race.Name = newName
+	race.QualyResultFile = replaceDir(race.QualyResultFile, oldDir, newDir)
+	race.RaceResultFile = replaceDir(race.RaceResultFile, oldDir, newDir)
+	race.LapDataFile = replaceDir(race.LapDataFile, oldDir, newDir)
+
+	s.Seasons[seasonName] = season
+	s.writeSeasonsFile()
 	return nil
 }
 
+// moveDataDir move a season or race directory, names mapping to
+// the same directory keep it
+func moveDataDir(oldDir string, newDir string) error {
+	if oldDir == newDir {
+		return nil
+	}
+	if _, err := os.Stat(newDir); err == nil {
+		return fmt.Errorf("data directory %v %w", newDir, ErrNotUnique)
+	}
+	if _, err := os.Stat(oldDir); err != nil {
+		return os.MkdirAll(newDir, 0770)
+	}
+	return os.Rename(oldDir, newDir)
+}
+
+func replaceDir(filename string, oldDir string, newDir string) string {
+	if filename == "" || !strings.HasPrefix(filename, oldDir+"/") {
+		return filename
+	}
+	return newDir + strings.TrimPrefix(filename, oldDir)
+}
+
 func (s *RaceData) readSeasonsFile() {
 	file, err := os.Open(s.RaceDataFile)
 	if err != nil {
-		log.Fatal(err)
+		fatal(s.logger, "can not open race data file", err)
 	}
 	defer file.Close()
 
 	b, err := io.ReadAll(file)
 	if err != nil {
-		log.Fatal(err)
+		fatal(s.logger, "can not read race data file", err)
 	}
 	json.Unmarshal(b, &s.Seasons)
 }
@@ -250,17 +549,52 @@ func (s *RaceData) readSeasonsFile() {
 func (s *RaceData) createSeasonsFile() {
 	f, err := os.Create(s.RaceDataFile)
 	if err != nil {
-		log.Fatal(err)
+		fatal(s.logger, "can not create race data file", err)
 	}
 	defer f.Close()
 }
 
-func (s *RaceData) dataDir(seaon string, race string) string {
-	return path.Join(s.DataDir,
-		strings.ToLower(strings.ReplaceAll(seaon, " ", "_")),
-		strings.ToLower(strings.ReplaceAll(race, " ", "_")))
+// seasonDir data directory of the season
+func (s *RaceData) seasonDir(season Season) string {
+	return path.Join(s.DataDir, season.Slug)
+}
+
+// raceDir data directory of the race
+func (s *RaceData) raceDir(season Season, race Race) string {
+	return path.Join(s.DataDir, season.Slug, race.Slug)
+}
+
+// checkName reject empty season and race names and names looking like
+// paths, all other characters are fine as the directories are named by slugs
+func checkName(name string) error {
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" || trimmed == "." || trimmed == ".." || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("name %q %w", name, ErrInvalidName)
+	}
+	return nil
+}
+
+// checkDataFile make sure a stored filename is inside the data directory
+// before it is read
+func (s *RaceData) checkDataFile(filename string) error {
+	dataDir, err := filepath.Abs(s.DataDir)
+	if err != nil {
+		return err
+	}
+	file, err := filepath.Abs(filename)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(dataDir, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("file %v is outside of the data directory", filename)
+	}
+	return nil
 }
 
-func nameToDir(name string) string {
-	return strings.ToLower(strings.ReplaceAll(name, " ", "_"))
+// fatal log the error and exit, continuing would leave
+// the data files in an inconsistent state
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,143 @@
+package racedata
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testEntryList = `driver,team,car,race_number,class
+Anna Fast,Team A,Porsche 911 GT3 R,1,GT3
+Ben Slow,Team B,Ferrari 296 GT3,2,GT3
+`
+
+// newTestRaceData race data in a temporary directory with one season,
+// one race and an entry list
+func newTestRaceData(t *testing.T) *RaceData {
+	t.Helper()
+	dir := t.TempDir()
+	rd := NewRaceData(filepath.Join(dir, "data"), filepath.Join(dir, "race_data.json"),
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if err := rd.AddSeason("Season 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.AddRace("Season 1", "Race 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.AddEntryList("Season 1", "", []byte(testEntryList)); err != nil {
+		t.Fatal(err)
+	}
+	return rd
+}
+
+func TestAddResultsEmptyPenalty(t *testing.T) {
+	rd := newTestRaceData(t)
+
+	result := []byte(`pos,participant,class,totalTime,bestLapTime,laps,penalty
+1,Team A,GT3,3600000,100000,30,
+2,Team B,GT3,3610000,101000,30,5
+`)
+	if err := rd.AddResults("Season 1", "Race 1", result, result); err != nil {
+		t.Fatal(err)
+	}
+
+	rr, err := rd.GetRaceResult("Season 1", "Race 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := rr.RaceResultWithPenalty["GT3"]
+	if len(lines) != 2 || lines[0].Penalty != "0" || lines[1].Penalty != "5" {
+		t.Errorf("unexpected result with penalty %+v", lines)
+	}
+	if lines[1].TotalTime != "01:00:15.000" {
+		t.Errorf("penalty not added to total time: %v", lines[1].TotalTime)
+	}
+}
+
+func TestValidateResultsRejectsNonNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		result string
+	}{
+		{"penalty", "pos,participant,class,totalTime,laps,penalty\n1,Team A,GT3,3600000,30,five\n"},
+		{"totalTime", "pos,participant,class,totalTime,laps\n1,Team A,GT3,1:00:00,30\n"},
+		{"laps", "pos,participant,class,totalTime,laps\n1,Team A,GT3,3600000,x\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			report := ValidateResults(SGP_FORMAT, []byte(tc.result), []byte(tc.result))
+			if report.Ok || report.Error() == nil {
+				t.Fatalf("invalid %v accepted", tc.name)
+			}
+		})
+	}
+}
+
+func TestStoredFilesOutsideDataDir(t *testing.T) {
+	rd := newTestRaceData(t)
+	secretFile := filepath.Join(filepath.Dir(rd.DataDir), "secret.csv")
+	if err := os.WriteFile(secretFile, []byte("driver,team\nsecret,secret\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	season := rd.Seasons["Season 1"]
+	for _, filename := range []string{secretFile, filepath.Join(rd.DataDir, "..", "secret.csv")} {
+		season.EntyListFile = filename
+		rd.Seasons["Season 1"] = season
+		if _, err := rd.GetSeasonEntryList("Season 1"); err == nil {
+			t.Errorf("entry list %v outside of the data directory was read", filename)
+		}
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	for _, name := range []string{"", " ", ".", "..", "../season", "/etc/passwd", `..\season`} {
+		if err := checkName(name); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("name %q accepted", name)
+		}
+	}
+	for _, name := range []string{"Season 1", "Race 1.5", "Spa 24h..."} {
+		if err := checkName(name); err != nil {
+			t.Errorf("name %q rejected - %v", name, err)
+		}
+	}
+}
+
+func TestAddEntryListUnknownDriverID(t *testing.T) {
+	rd := newTestRaceData(t)
+	season := rd.Seasons["Season 1"]
+	before, err := os.ReadFile(season.EntyListFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, err := os.ReadFile(rd.driverRegistryFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	drivers := len(rd.drivers)
+
+	entryList := []byte("driver,team,driver_id\nCarl New,Team C,\nDora Linked,Team D,no-such-driver\n")
+	if err := rd.AddEntryList("Season 1", "", entryList); err == nil {
+		t.Fatal("unknown driver id accepted")
+	}
+
+	after, err := os.ReadFile(season.EntyListFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("entry list overwritten\n%s", after)
+	}
+	if rd.Seasons["Season 1"].EntyListFile != season.EntyListFile {
+		t.Errorf("entry list file changed to %v", rd.Seasons["Season 1"].EntyListFile)
+	}
+	if len(rd.drivers) != drivers {
+		t.Errorf("drivers registered %+v", rd.drivers[drivers:])
+	}
+	if b, _ := os.ReadFile(rd.driverRegistryFile()); string(b) != string(registry) {
+		t.Errorf("driver registry written\n%s", b)
+	}
+}
@@ -3,43 +3,64 @@ package racedata
 import (
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"sort"
 	"strconv"
 )
 
 // RaceResult data struct to send race data to html template
 type RaceResult struct {
-	SeasonName            string
-	RaceName              string
-	QualiyResult          map[string]ResultLines
-	RaceResult            map[string]ResultLines
-	RaceResultWithPenalty map[string]ResultLines
+	SeasonName            string                 `json:"season"`
+	RaceName              string                 `json:"race"`
+	SeasonSlug            string                 `json:"season_slug"`
+	RaceSlug              string                 `json:"race_slug"`
+	QualiyResult          map[string]ResultLines `json:"qualy_result,omitempty"`
+	RaceResult            map[string]ResultLines `json:"race_result,omitempty"`
+	RaceResultWithPenalty map[string]ResultLines `json:"race_result_with_penalty,omitempty"`
+	// Pole qualifying winner of every split
+	Pole map[string]ResultLine `json:"pole,omitempty"`
+	// FastestLap and FastestCleanLap race award of every split
+	FastestLap      map[string]ResultLine `json:"fastest_lap,omitempty"`
+	FastestCleanLap map[string]ResultLine `json:"fastest_clean_lap,omitempty"`
 }
 
 type Driver struct {
-	Driver     string
-	Team       string
-	Car        string
-	RaceNumber string
-	Class      string
+	Driver     string `json:"driver"`
+	Team       string `json:"team"`
+	Car        string `json:"car"`
+	RaceNumber string `json:"race_number"`
+	Class      string `json:"class"`
+	// DriverID registered driver, empty for unknown drivers
+	DriverID string `json:"driver_id,omitempty"`
+	// TeamID registered team, empty for unknown teams
+	TeamID string `json:"team_id,omitempty"`
 }
 
 type EntryList []Driver
 
 type ResultLine struct {
-	Pos              uint
-	StartPos         string
-	Driver           string
-	Team             string
-	Startnumber      string
-	Car              string
-	Class            string
-	TotalTime        string
-	BestLapTime      string
-	BestCleanLapTime string
-	Laps             string
-	Penalty          string
+	Pos              uint   `json:"pos"`
+	StartPos         string `json:"start_pos"`
+	Driver           string `json:"driver"`
+	DriverID         string `json:"driver_id,omitempty"`
+	Team             string `json:"team"`
+	TeamID           string `json:"team_id,omitempty"`
+	Startnumber      string `json:"race_number"`
+	Car              string `json:"car"`
+	Class            string `json:"class"`
+	TotalTime        string `json:"total_time"`
+	BestLapTime      string `json:"best_lap_time"`
+	BestCleanLapTime string `json:"best_clean_lap_time"`
+	Laps             string `json:"laps"`
+	Penalty          string `json:"penalty"`
+	// Gap to the leader or pole and Interval to the car ahead
+	Gap      string `json:"gap,omitempty"`
+	Interval string `json:"interval,omitempty"`
+	// PositionsGained start position minus finishing position, both within the split
+	PositionsGained int `json:"positions_gained"`
+	// FastestLap and FastestCleanLap the car set the fastest (clean) lap of the split
+	FastestLap      bool `json:"fastest_lap,omitempty"`
+	FastestCleanLap bool `json:"fastest_clean_lap,omitempty"`
 }
 
 type ResultLines []ResultLine
@@ -51,8 +72,8 @@ func (r ResultLines) Len() int {
 // Less sort totalTime first, lap count second
 func (r ResultLines) Less(i, j int) bool {
 
-	ti, _ := strconv.Atoi(r[i].TotalTime)
-	tj, _ := strconv.Atoi(r[j].TotalTime)
+	ti, errI := strconv.Atoi(r[i].TotalTime)
+	tj, errJ := strconv.Atoi(r[j].TotalTime)
 
 	li, _ := strconv.Atoi(r[i].Laps)
 	lj, _ := strconv.Atoi(r[j].Laps)
@@ -60,6 +81,10 @@ func (r ResultLines) Less(i, j int) bool {
 	if li != lj {
 		return li > lj
 	}
+	// cars without a total time did not finish, behind the cars on the same lap
+	if (errI == nil) != (errJ == nil) {
+		return errI == nil
+	}
 
 	return ti < tj
 
@@ -69,7 +94,12 @@ func (r ResultLines) Swap(i, j int) {
 	r[i], r[j] = r[j], r[i]
 }
 
-func (s *RaceData) GetEntryList(filename string) (*EntryList, error) {
+// entryList read an entry list file of the data directory
+func (s *RaceData) entryList(filename string) (*EntryList, error) {
+	if err := s.checkDataFile(filename); err != nil {
+		return nil, err
+	}
+
 	csvEntryList, err := readEntryList(filename)
 	if err != nil {
 		return nil, fmt.Errorf(err.Error())
@@ -84,22 +114,48 @@ func (s *RaceData) GetEntryList(filename string) (*EntryList, error) {
 			Car:        line.Car,
 			RaceNumber: line.RaceNumber,
 			Class:      line.Class,
+			DriverID:   s.resolveDriverID(line.DriverID, line.Driver),
+			TeamID:     s.teamID(line.Team),
 		})
 	}
 
 	return &entryList, nil
 }
 
+// GetSeasonEntryList entry list of the season, empty if none was uploaded
+func (s *RaceData) GetSeasonEntryList(seasonName string) (*EntryList, error) {
+	filename, err := s.GetEntryListFilename(seasonName)
+	if err != nil {
+		return nil, err
+	}
+	if filename == "" {
+		return &EntryList{}, nil
+	}
+	return s.entryList(filename)
+}
+
 func (s *RaceData) GetRaceResult(seasonName string, raceName string) (*RaceResult, error) {
 
 	qualyFilename, raceFilename, err := s.GetResultFilenames(seasonName, raceName)
 	if err != nil {
-		return nil, fmt.Errorf("season %v and race %v", seasonName, raceName)
+		return nil, err
+	}
+	if raceFilename == "" {
+		return nil, fmt.Errorf("results of race %v in season %v %w", raceName, seasonName, ErrNotFound)
 	}
 
 	entyListFilename, err := s.GetEntryListFilename(seasonName)
 	if err != nil {
-		return nil, fmt.Errorf("no entry list found for season %v", seasonName)
+		return nil, err
+	}
+	if entyListFilename == "" {
+		return nil, fmt.Errorf("entry list of season %v %w", seasonName, ErrNotFound)
+	}
+
+	for _, filename := range []string{qualyFilename, raceFilename, entyListFilename} {
+		if err := s.checkDataFile(filename); err != nil {
+			return nil, err
+		}
 	}
 
 	qualyResult, err := readResult(qualyFilename)
@@ -116,10 +172,23 @@ func (s *RaceData) GetRaceResult(seasonName string, raceName string) (*RaceResul
 	if err != nil {
 		return nil, fmt.Errorf("can not read entry list %v", entryList)
 	}
+	for i, line := range *entryList {
+		(*entryList)[i].DriverID = s.resolveDriverID(line.DriverID, line.Driver)
+	}
 
-	rr := toRaceResult(qualyResult, raceResult, entryList)
+	rr, err := toRaceResult(qualyResult, raceResult, entryList)
+	if err != nil {
+		return nil, fmt.Errorf("race result file %v - %v", raceFilename, err)
+	}
+	s.addTeamIDs(rr)
 	rr.RaceName = raceName
 	rr.SeasonName = seasonName
+	rr.SeasonSlug = s.Seasons[seasonName].Slug
+	for _, race := range s.Seasons[seasonName].Races {
+		if race.Name == raceName {
+			rr.RaceSlug = race.Slug
+		}
+	}
 
 	return rr, nil
 
@@ -129,19 +198,22 @@ func (r *ResultLine) addDriverAndRaceNumber(el *CSVEntryList) {
 	for _, v := range *el {
 		if v.Team == r.Team {
 			r.Driver = v.Driver
+			r.DriverID = v.DriverID
 			r.Startnumber = v.RaceNumber
 			return
 		}
 	}
-	r.Driver = "N/A"
+	r.Driver = UNKNOWN_DRIVER
 }
 
-func toRaceResult(qr *CSVResult, rr *CSVResult, el *CSVEntryList) *RaceResult {
+func toRaceResult(qr *CSVResult, rr *CSVResult, el *CSVEntryList) (*RaceResult, error) {
 
 	raceResult := &RaceResult{
+		QualiyResult:          qualifyingResult(qr, el),
 		RaceResult:            map[string]ResultLines{},
 		RaceResultWithPenalty: map[string]ResultLines{},
 	}
+	raceResult.Pole = poles(raceResult.QualiyResult)
 
 	for _, line := range *rr {
 		_, found := raceResult.RaceResult[line.Class]
@@ -159,16 +231,17 @@ func toRaceResult(qr *CSVResult, rr *CSVResult, el *CSVEntryList) *RaceResult {
 		for _, v := range raceResult.RaceResult[k] {
 
 			resultLine := v
-			if resultLine.Penalty != "0" {
+			// cars without a total time did not finish, nothing to add to
+			if resultLine.Penalty != "" && resultLine.Penalty != "0" && resultLine.TotalTime != "" {
 				p, err := strconv.Atoi(resultLine.Penalty)
 				if err != nil {
-					log.Fatal(err)
+					return nil, fmt.Errorf("can not convert penalty of pos %v - %v", resultLine.Pos, err)
 				}
 				t, err := strconv.Atoi(resultLine.TotalTime)
 				if err != nil {
-					log.Fatal(err)
+					return nil, fmt.Errorf("can not convert total time of pos %v - %v", resultLine.Pos, err)
 				}
-				log.Printf("total time: %v + %v = %v\n", resultLine.TotalTime, (p * 1000), (t + (p * 1000)))
+				slog.Debug("add penalty to total time", "total_time", resultLine.TotalTime, "penalty_ms", p*1000, "result", t+(p*1000))
 				resultLine.TotalTime = fmt.Sprintf("%v", t+(p*1000))
 			}
 			raceResult.RaceResultWithPenalty[k] = append(raceResult.RaceResultWithPenalty[k], resultLine)
@@ -177,20 +250,26 @@ func toRaceResult(qr *CSVResult, rr *CSVResult, el *CSVEntryList) *RaceResult {
 
 	for k := range raceResult.RaceResultWithPenalty {
 		sort.Sort(raceResult.RaceResultWithPenalty[k])
+		addGaps(raceResult.RaceResultWithPenalty[k])
+		markFastestLaps(raceResult.RaceResult[k])
+		markFastestLaps(raceResult.RaceResultWithPenalty[k])
 	}
 
 	for k := range raceResult.RaceResultWithPenalty {
 		for i := range raceResult.RaceResultWithPenalty[k] {
 			raceResult.RaceResultWithPenalty[k][i].BestLapTime = convertMilliseconds(raceResult.RaceResultWithPenalty[k][i].BestLapTime)
 			raceResult.RaceResultWithPenalty[k][i].TotalTime = convertMilliseconds(raceResult.RaceResultWithPenalty[k][i].TotalTime)
+			raceResult.RaceResultWithPenalty[k][i].BestCleanLapTime = convertLapTime(raceResult.RaceResultWithPenalty[k][i].BestCleanLapTime)
 
 			raceResult.RaceResult[k][i].BestLapTime = convertMilliseconds(raceResult.RaceResult[k][i].BestLapTime)
 			raceResult.RaceResult[k][i].TotalTime = convertMilliseconds(raceResult.RaceResult[k][i].TotalTime)
+			raceResult.RaceResult[k][i].BestCleanLapTime = convertLapTime(raceResult.RaceResult[k][i].BestCleanLapTime)
 		}
 
 	}
+	raceResult.FastestLap, raceResult.FastestCleanLap = fastestLaps(raceResult.RaceResultWithPenalty)
 
-	return raceResult
+	return raceResult, nil
 }
 
 func csvResultToResultLine(line CSVResultLine) ResultLine {
@@ -208,9 +287,69 @@ func csvResultToResultLine(line CSVResultLine) ResultLine {
 	}
 }
 
+// addGaps gap to the leader, interval to the car ahead and positions
+// gained of the sorted result lines, total times in milliseconds
+func addGaps(lines ResultLines) {
+	ranks := startRanks(lines)
+	for i := range lines {
+		if ranks[i] > 0 {
+			lines[i].PositionsGained = ranks[i] - (i + 1)
+		}
+		if i == 0 {
+			continue
+		}
+		lines[i].Gap = raceGap(lines[0], lines[i])
+		lines[i].Interval = raceGap(lines[i-1], lines[i])
+	}
+}
+
+// startRanks start position of the lines within the split, the start
+// positions of the result are the positions on the overall grid. 0 for
+// lines without a start position
+func startRanks(lines ResultLines) []int {
+	type start struct {
+		line int
+		pos  int
+	}
+	starts := []start{}
+	for i, line := range lines {
+		if pos, err := strconv.Atoi(line.StartPos); err == nil && pos > 0 {
+			starts = append(starts, start{line: i, pos: pos})
+		}
+	}
+	sort.SliceStable(starts, func(i, j int) bool { return starts[i].pos < starts[j].pos })
+
+	ranks := make([]int, len(lines))
+	for rank, s := range starts {
+		ranks[s.line] = rank + 1
+	}
+	return ranks
+}
+
+// raceGap time gap of the line to the line ahead or +N laps if lapped,
+// DNF for cars on the same lap without a total time
+func raceGap(ahead ResultLine, line ResultLine) string {
+	aheadLaps, _ := strconv.Atoi(ahead.Laps)
+	laps, _ := strconv.Atoi(line.Laps)
+	if down := aheadLaps - laps; down == 1 {
+		return "+1 lap"
+	} else if down > 1 {
+		return fmt.Sprintf("+%d laps", down)
+	}
+	t, err := strconv.Atoi(line.TotalTime)
+	if err != nil {
+		return "DNF"
+	}
+	aheadTime, err := strconv.Atoi(ahead.TotalTime)
+	if err != nil {
+		return ""
+	}
+	return formatGap(t - aheadTime)
+}
+
 func GetCSVExport(raceResult *RaceResult, split string, w io.Writer) {
 
-	fmt.Fprintf(w, "split pos, race pos,laps,race number,team,driver,penalty,ziel zeit\n")
+	fmt.Fprintf(w, "split pos, race pos,laps,race number,team,driver,penalty,ziel zeit,gap,interval,positions gained,best lap,best clean lap,fastest lap,fastest clean lap\n")
 	resultLines := raceResult.RaceResultWithPenalty[split]
 
 	for i, line := range resultLines {
@@ -218,7 +357,8 @@ func GetCSVExport(raceResult *RaceResult, split string, w io.Writer) {
 			continue
 		}
 		// TODO check amount of laps more than 50%
-		fmt.Fprintf(w, "%v,%v,%v,%v,%v,%v,%v,%v\n", i+1, line.Pos, line.Laps, line.Startnumber, line.Team, line.Driver, line.Penalty, line.TotalTime)
+		fmt.Fprintf(w, "%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v,%v\n", i+1, line.Pos, line.Laps, line.Startnumber, line.Team, line.Driver, line.Penalty, line.TotalTime, line.Gap, line.Interval, line.PositionsGained,
+			line.BestLapTime, line.BestCleanLapTime, csvFlag(line.FastestLap), csvFlag(line.FastestCleanLap))
 	}
 
 }
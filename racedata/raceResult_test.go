@@ -0,0 +1,113 @@
+package racedata
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddGaps(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		lines     ResultLines
+		gaps      []string
+		intervals []string
+		gained    []int
+	}{
+		{
+			name: "same lap",
+			lines: ResultLines{
+				{StartPos: "2", Laps: "30", TotalTime: "3600000"},
+				{StartPos: "1", Laps: "30", TotalTime: "3601500"},
+				{StartPos: "3", Laps: "30", TotalTime: "3665250"},
+			},
+			gaps:      []string{"", "+1.500", "+1:05.250"},
+			intervals: []string{"", "+1.500", "+1:03.750"},
+			gained:    []int{1, -1, 0},
+		},
+		{
+			name: "lapped cars",
+			lines: ResultLines{
+				{Laps: "30", TotalTime: "3600000"},
+				{Laps: "29", TotalTime: "3590000"},
+				{Laps: "27", TotalTime: "3610000"},
+			},
+			gaps:      []string{"", "+1 lap", "+3 laps"},
+			intervals: []string{"", "+1 lap", "+2 laps"},
+			gained:    []int{0, 0, 0},
+		},
+		{
+			name: "dnf without total time",
+			lines: ResultLines{
+				{Laps: "30", TotalTime: "3600000"},
+				{Laps: "30", TotalTime: ""},
+				{Laps: "12", TotalTime: ""},
+			},
+			gaps:      []string{"", "DNF", "+18 laps"},
+			intervals: []string{"", "DNF", "+18 laps"},
+			gained:    []int{0, 0, 0},
+		},
+		{
+			// class split of a multi class race, start positions on the overall grid
+			name: "split of the overall grid",
+			lines: ResultLines{
+				{StartPos: "20", Laps: "28", TotalTime: "3700000"},
+				{StartPos: "15", Laps: "28", TotalTime: "3705000"},
+				{StartPos: "", Laps: "28", TotalTime: "3706000"},
+				{StartPos: "18", Laps: "28", TotalTime: "3710000"},
+			},
+			gaps:      []string{"", "+5.000", "+6.000", "+10.000"},
+			intervals: []string{"", "+5.000", "+1.000", "+4.000"},
+			gained:    []int{2, -1, 0, -2},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			addGaps(tc.lines)
+			gaps, intervals, gained := []string{}, []string{}, []int{}
+			for _, line := range tc.lines {
+				gaps = append(gaps, line.Gap)
+				intervals = append(intervals, line.Interval)
+				gained = append(gained, line.PositionsGained)
+			}
+			if !reflect.DeepEqual(gaps, tc.gaps) {
+				t.Errorf("gaps %q, want %q", gaps, tc.gaps)
+			}
+			if !reflect.DeepEqual(intervals, tc.intervals) {
+				t.Errorf("intervals %q, want %q", intervals, tc.intervals)
+			}
+			if !reflect.DeepEqual(gained, tc.gained) {
+				t.Errorf("positions gained %v, want %v", gained, tc.gained)
+			}
+		})
+	}
+}
+
+func TestRaceResultPenaltyReorder(t *testing.T) {
+	result := &CSVResult{
+		{Pos: 1, StartPos: "3", Participant: "Team A", Class: "GT3", TotalTime: "3600000", Laps: "30", Penalty: "10"},
+		{Pos: 2, StartPos: "1", Participant: "Team B", Class: "GT3", TotalTime: "3605000", Laps: "30", Penalty: "0"},
+		{Pos: 3, StartPos: "2", Participant: "Team C", Class: "GT3", TotalTime: "", Laps: "30", Penalty: "5"},
+		{Pos: 4, StartPos: "4", Participant: "Team D", Class: "GT3", TotalTime: "3300000", Laps: "28", Penalty: "0"},
+	}
+	rr, err := toRaceResult(&CSVResult{}, result, &CSVEntryList{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type row struct {
+		Team, Gap, Interval string
+		Gained              int
+	}
+	got := []row{}
+	for _, line := range rr.RaceResultWithPenalty["GT3"] {
+		got = append(got, row{line.Team, line.Gap, line.Interval, line.PositionsGained})
+	}
+	want := []row{
+		{"Team B", "", "", 0},
+		{"Team A", "+5.000", "+5.000", 1},
+		{"Team C", "DNF", "DNF", -1},
+		{"Team D", "+2 laps", "+2 laps", 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("result with penalty\n%+v\nwant\n%+v", got, want)
+	}
+}
@@ -0,0 +1,324 @@
+package racedata
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DRIVER_REGISTRY_FILE drivers of all seasons, stored next to the race data file
+const DRIVER_REGISTRY_FILE = "drivers.json"
+
+// MAX_DRIVER_FIELD_LENGTH max length of driver names, aliases,
+// simracing.gp user ids and nationalities
+const MAX_DRIVER_FIELD_LENGTH = 100
+
+// RegisteredDriver person behind the driver names of the entry lists,
+// entry lists link to the id by the driver_id column or by name and
+// aliases. Merged duplicates keep their id with MergedInto set
+type RegisteredDriver struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases,omitempty"`
+	SGPUserID   string   `json:"sgp_user_id,omitempty"`
+	Nationality string   `json:"nationality,omitempty"`
+	MergedInto  string   `json:"merged_into,omitempty"`
+}
+
+// names name and aliases of the driver
+func (d RegisteredDriver) names() []string {
+	return append([]string{d.Name}, d.Aliases...)
+}
+
+// GetDrivers registered drivers without merged duplicates, sorted by name
+func (s *RaceData) GetDrivers() []RegisteredDriver {
+	drivers := []RegisteredDriver{}
+	for _, d := range s.drivers {
+		if d.MergedInto == "" {
+			drivers = append(drivers, d)
+		}
+	}
+	sort.Slice(drivers, func(i, j int) bool {
+		return strings.ToLower(drivers[i].Name) < strings.ToLower(drivers[j].Name)
+	})
+	return drivers
+}
+
+// GetDriver registered driver, merged duplicates resolve to the
+// driver they were merged into
+func (s *RaceData) GetDriver(id string) (RegisteredDriver, error) {
+	i := s.driverIndex(s.canonicalDriverID(id))
+	if i == -1 {
+		return RegisteredDriver{}, fmt.Errorf("driver %v %w", id, ErrNotFound)
+	}
+	return s.drivers[i], nil
+}
+
+// UpdateDriver change name, aliases, simracing.gp user id and nationality
+// of the driver, a changed name is kept as alias so older entry lists
+// still link to the driver
+func (s *RaceData) UpdateDriver(id string, update RegisteredDriver) error {
+	i := s.driverIndex(id)
+	if i == -1 || s.drivers[i].MergedInto != "" {
+		return fmt.Errorf("driver %v %w", id, ErrNotFound)
+	}
+	driver := s.drivers[i]
+
+	update.Name = strings.TrimSpace(update.Name)
+	if update.Name == "" || update.Name == UNKNOWN_DRIVER {
+		return fmt.Errorf("driver name %q %w", update.Name, ErrInvalidName)
+	}
+	aliases := []string{}
+	for _, alias := range update.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias != "" && !strings.EqualFold(alias, update.Name) && !containsFold(aliases, alias) {
+			aliases = append(aliases, alias)
+		}
+	}
+	if !strings.EqualFold(driver.Name, update.Name) && !containsFold(aliases, driver.Name) {
+		aliases = append(aliases, driver.Name)
+	}
+
+	driver.Name = update.Name
+	driver.Aliases = aliases
+	driver.SGPUserID = strings.TrimSpace(update.SGPUserID)
+	driver.Nationality = strings.TrimSpace(update.Nationality)
+	for _, field := range append(driver.names(), driver.SGPUserID, driver.Nationality) {
+		if len(field) > MAX_DRIVER_FIELD_LENGTH {
+			return fmt.Errorf("%q is longer than %v characters", field, MAX_DRIVER_FIELD_LENGTH)
+		}
+	}
+
+	for _, other := range s.drivers {
+		if other.ID == id || other.MergedInto != "" {
+			continue
+		}
+		for _, name := range driver.names() {
+			if containsFold(other.names(), name) {
+				return fmt.Errorf("driver name %v of %v %w, merge the drivers instead", name, other.ID, ErrNotUnique)
+			}
+		}
+		if driver.SGPUserID != "" && other.SGPUserID == driver.SGPUserID {
+			return fmt.Errorf("simracing.gp user id %v of %v %w", driver.SGPUserID, other.ID, ErrNotUnique)
+		}
+	}
+
+	s.logger.Info("update driver", "driver", id, "name", driver.Name)
+	s.drivers[i] = driver
+	s.writeDriverRegistry()
+	return nil
+}
+
+// MergeDrivers merge the duplicate into the driver, names become aliases,
+// empty fields are taken from the duplicate. Entry lists linking to the
+// duplicate follow it to the driver
+func (s *RaceData) MergeDrivers(duplicateID string, intoID string) error {
+	from := s.driverIndex(duplicateID)
+	into := s.driverIndex(intoID)
+	if from == -1 || s.drivers[from].MergedInto != "" {
+		return fmt.Errorf("driver %v %w", duplicateID, ErrNotFound)
+	}
+	if into == -1 || s.drivers[into].MergedInto != "" {
+		return fmt.Errorf("driver %v %w", intoID, ErrNotFound)
+	}
+	if from == into {
+		return fmt.Errorf("can not merge driver %v into itself", duplicateID)
+	}
+	s.logger.Info("merge drivers", "duplicate", duplicateID, "into", intoID)
+
+	duplicate := &s.drivers[from]
+	driver := &s.drivers[into]
+	for _, name := range duplicate.names() {
+		if !containsFold(driver.names(), name) {
+			driver.Aliases = append(driver.Aliases, name)
+		}
+	}
+	if driver.SGPUserID == "" {
+		driver.SGPUserID = duplicate.SGPUserID
+	}
+	if driver.Nationality == "" {
+		driver.Nationality = duplicate.Nationality
+	}
+
+	duplicate.Aliases = nil
+	duplicate.SGPUserID = ""
+	duplicate.MergedInto = intoID
+	for i := range s.drivers {
+		if s.drivers[i].MergedInto == duplicateID {
+			s.drivers[i].MergedInto = intoID
+		}
+	}
+	s.writeDriverRegistry()
+	return nil
+}
+
+func (s *RaceData) driverIndex(id string) int {
+	for i, d := range s.drivers {
+		if d.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// canonicalDriverID id of the driver a duplicate was merged into
+func (s *RaceData) canonicalDriverID(id string) string {
+	for range s.drivers {
+		i := s.driverIndex(id)
+		if i == -1 || s.drivers[i].MergedInto == "" {
+			return id
+		}
+		id = s.drivers[i].MergedInto
+	}
+	return id
+}
+
+// driverIDByName id of the driver with the name or alias, empty if unknown
+func (s *RaceData) driverIDByName(name string) string {
+	name = strings.TrimSpace(name)
+	for _, d := range s.drivers {
+		if d.MergedInto == "" && containsFold(d.names(), name) {
+			return d.ID
+		}
+	}
+	return ""
+}
+
+// resolveDriverID registered id of an entry list driver, the linked id if
+// set, otherwise the driver with the name. Empty for unknown drivers
+func (s *RaceData) resolveDriverID(id string, name string) string {
+	if id != "" {
+		return s.canonicalDriverID(id)
+	}
+	if name == "" || name == UNKNOWN_DRIVER {
+		return ""
+	}
+	return s.driverIDByName(name)
+}
+
+// registerDriver id of the driver with the name, new drivers are added
+// with the slug of the name as id. The registry file is written by the caller
+func (s *RaceData) registerDriver(name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" || name == UNKNOWN_DRIVER {
+		return ""
+	}
+	if id := s.driverIDByName(name); id != "" {
+		return id
+	}
+	id := uniqueSlug(name, "driver", func(slug string) bool {
+		return s.driverIndex(slug) != -1
+	})
+	s.drivers = append(s.drivers, RegisteredDriver{ID: id, Name: name})
+	return id
+}
+
+// linkEntryListDrivers fill the driver_id column of the normalized entry
+// list, drivers are registered if unknown. Linked ids must be registered,
+// they are checked before any driver is registered. The registry file is
+// written by the caller
+func (s *RaceData) linkEntryListDrivers(entryList []byte) ([]byte, error) {
+	records, err := csv.NewReader(bytes.NewReader(entryList)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	driverColumn := columnIndex(records[0], "driver")
+	idColumn := columnIndex(records[0], "driver_id")
+	if idColumn == -1 {
+		idColumn = len(records[0])
+		records[0] = append(records[0], "driver_id")
+	}
+
+	for i := 1; i < len(records); i++ {
+		for len(records[i]) <= idColumn {
+			records[i] = append(records[i], "")
+		}
+		records[i][idColumn] = strings.TrimSpace(records[i][idColumn])
+		if id := records[i][idColumn]; id != "" && s.driverIndex(id) == -1 {
+			return nil, fmt.Errorf("driver id %v %w", id, ErrNotFound)
+		}
+	}
+	for i := 1; i < len(records); i++ {
+		if records[i][idColumn] == "" {
+			records[i][idColumn] = s.registerDriver(records[i][driverColumn])
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := csv.NewWriter(buf).WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// migrateDriverRegistry register the drivers of entry lists uploaded
+// before the registry, the entry lists link to them by name. Linked ids
+// missing in the registry, e.g. of a lost registry file, are added again
+func (s *RaceData) migrateDriverRegistry() {
+	registered := len(s.drivers)
+	for _, seasonName := range s.sortedSeasonNames() {
+		filename := s.Seasons[seasonName].EntyListFile
+		if filename == "" || s.checkDataFile(filename) != nil {
+			continue
+		}
+		entryList, err := readEntryList(filename)
+		if err != nil {
+			s.logger.Warn("can not register drivers of entry list", "season", seasonName, "error", err)
+			continue
+		}
+		for _, line := range *entryList {
+			if line.DriverID == "" {
+				s.registerDriver(line.Driver)
+			} else if s.driverIndex(line.DriverID) == -1 {
+				s.drivers = append(s.drivers, RegisteredDriver{ID: line.DriverID, Name: strings.TrimSpace(line.Driver)})
+			}
+		}
+	}
+	if len(s.drivers) > registered {
+		s.logger.Info("registered drivers of existing entry lists", "drivers", len(s.drivers)-registered)
+		s.writeDriverRegistry()
+	}
+}
+
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *RaceData) driverRegistryFile() string {
+	return path.Join(path.Dir(s.RaceDataFile), DRIVER_REGISTRY_FILE)
+}
+
+func (s *RaceData) readDriverRegistry() {
+	b, err := os.ReadFile(s.driverRegistryFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		fatal(s.logger, "can not read driver registry", err)
+	}
+	if err := json.Unmarshal(b, &s.drivers); err != nil {
+		fatal(s.logger, "can not decode driver registry", err)
+	}
+}
+
+func (s *RaceData) writeDriverRegistry() {
+	b, err := json.MarshalIndent(s.drivers, "", "   ")
+	if err != nil {
+		fatal(s.logger, "can not encode driver registry", err)
+	}
+	if err := os.WriteFile(s.driverRegistryFile(), b, 0644); err != nil {
+		fatal(s.logger, "can not write driver registry", err)
+	}
+}
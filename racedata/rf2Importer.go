@@ -0,0 +1,125 @@
+package racedata
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RF2_FORMAT name of the rFactor 2 / Le Mans Ultimate xml result format
+const RF2_FORMAT = "rf2"
+
+type rf2ResultFile struct {
+	RaceResults rf2RaceResults `xml:"RaceResults"`
+}
+
+type rf2RaceResults struct {
+	Race     *rf2Session `xml:"Race"`
+	Qualify  *rf2Session `xml:"Qualify"`
+	Practice *rf2Session `xml:"Practice1"`
+}
+
+type rf2Session struct {
+	Drivers []rf2Driver `xml:"Driver"`
+}
+
+type rf2Driver struct {
+	Name         string   `xml:"Name"`
+	VehName      string   `xml:"VehName"`
+	CarType      string   `xml:"CarType"`
+	CarClass     string   `xml:"CarClass"`
+	CarNumber    string   `xml:"CarNumber"`
+	TeamName     string   `xml:"TeamName"`
+	GridPos      string   `xml:"GridPos"`
+	Position     uint     `xml:"Position"`
+	BestLapTime  float64  `xml:"BestLapTime"`
+	FinishTime   float64  `xml:"FinishTime"`
+	Laps         string   `xml:"Laps"`
+	FinishStatus string   `xml:"FinishStatus"`
+	Lap          []rf2Lap `xml:"Lap"`
+}
+
+type rf2Lap struct {
+	Time string `xml:",chardata"`
+}
+
+// rf2Importer rFactor 2 and Le Mans Ultimate results, the race session
+// is used if the file contains one, otherwise qualifying or practice
+type rf2Importer struct{}
+
+func (rf2Importer) Name() string {
+	return RF2_FORMAT
+}
+
+func (rf2Importer) Import(data []byte) (*CSVResult, error) {
+	var file rf2ResultFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("can not parse rfactor 2 result - %v", err)
+	}
+
+	session := file.RaceResults.Race
+	if session == nil {
+		session = file.RaceResults.Qualify
+	}
+	if session == nil {
+		session = file.RaceResults.Practice
+	}
+	if session == nil || len(session.Drivers) == 0 {
+		return nil, fmt.Errorf("rfactor 2 result contains no session with drivers")
+	}
+
+	drivers := session.Drivers
+	sort.SliceStable(drivers, func(i, j int) bool {
+		return drivers[i].Position < drivers[j].Position
+	})
+
+	result := CSVResult{}
+	for _, d := range drivers {
+		participant := d.TeamName
+		if participant == "" {
+			participant = d.Name
+		}
+		car := d.VehName
+		if car == "" {
+			car = d.CarType
+		}
+		class := d.CarClass
+		if class == "" {
+			class = DEFAULT_CLASS
+		}
+
+		result = append(result, CSVResultLine{
+			Pos:              d.Position,
+			StartPos:         d.GridPos,
+			Participant:      participant,
+			Driver:           d.Name,
+			CarNumber:        d.CarNumber,
+			Car:              car,
+			Class:            class,
+			TotalTime:        secondsToMilliseconds(d.FinishTime),
+			BestLapTime:      secondsToMilliseconds(d.BestLapTime),
+			BestCleanLapTime: secondsToMilliseconds(d.bestValidLap()),
+			Laps:             d.Laps,
+		})
+	}
+
+	return &result, nil
+}
+
+// bestValidLap fastest lap with a recorded time, invalid laps
+// are written as "--.----" by the game
+func (d rf2Driver) bestValidLap() float64 {
+	best := 0.0
+	for _, lap := range d.Lap {
+		t, err := strconv.ParseFloat(strings.TrimSpace(lap.Time), 64)
+		if err != nil || t <= 0 {
+			continue
+		}
+		if best == 0 || t < best {
+			best = t
+		}
+	}
+	return best
+}
@@ -0,0 +1,341 @@
+package racedata
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MAX_ARCHIVE_FILE_SIZE max uncompressed size of a single file in a season archive
+const MAX_ARCHIVE_FILE_SIZE = 10 * 1024 * 1024 // 10MB
+
+// MAX_ARCHIVE_SIZE max uncompressed size of all files in a season archive
+const MAX_ARCHIVE_SIZE = 100 * 1024 * 1024 // 100MB
+
+// MAX_ARCHIVE_ENTRIES max number of files and folders in a season archive
+const MAX_ARCHIVE_ENTRIES = 1000
+
+// ARCHIVE_MANIFEST optional manifest file in the root of a season archive
+const ARCHIVE_MANIFEST = "manifest.json"
+
+// ArchiveManifest race order, result formats and penalties of a season archive
+type ArchiveManifest struct {
+	EntryList string        `json:"entry_list"`
+	Races     []ArchiveRace `json:"races"`
+}
+
+type ArchiveRace struct {
+	Name      string           `json:"name"`
+	Dir       string           `json:"dir"`
+	Format    string           `json:"format"`
+	Qualy     string           `json:"qualy"`
+	Race      string           `json:"race"`
+	Penalties []ArchivePenalty `json:"penalties"`
+}
+
+// ArchivePenalty penalty of the race result, or of the qualifying
+// result with session qualy
+type ArchivePenalty struct {
+	Pos     string `json:"pos"`
+	Penalty string `json:"penalty"`
+	Session string `json:"session,omitempty"`
+}
+
+// ImportReport result of a season archive import, one line per file
+type ImportReport struct {
+	SeasonName string
+	Lines      []ImportReportLine
+	// Removed no race could be imported and the season was removed again
+	Removed bool
+}
+
+type ImportReportLine struct {
+	File    string
+	Race    string
+	Ok      bool
+	Message string
+}
+
+func (r *ImportReport) add(file string, race string, err error, message string) {
+	line := ImportReportLine{File: file, Race: race, Ok: err == nil, Message: message}
+	if err != nil {
+		line.Message = err.Error()
+	}
+	r.Lines = append(r.Lines, line)
+}
+
+// Failed number of files which could not be imported
+func (r *ImportReport) Failed() int {
+	failed := 0
+	for _, line := range r.Lines {
+		if !line.Ok {
+			failed++
+		}
+	}
+	return failed
+}
+
+// ImportSeasonArchive create a new season with entry list and races from a zip
+// archive with the entry list in the root and one folder per race containing
+// the qualy and race results, an optional manifest.json defines race order,
+// result formats and penalties
+func (s *RaceData) ImportSeasonArchive(seasonName string, archive []byte) (*ImportReport, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("can not open season archive - %v", err)
+	}
+
+	files, err := readArchiveFiles(zr)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := archiveManifest(files)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.AddSeason(seasonName); err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{SeasonName: seasonName}
+
+	if manifest.EntryList == "" {
+		report.add("", "", fmt.Errorf("no entry list found in archive root"), "")
+	} else {
+		err := s.AddEntryList(seasonName, "", files[manifest.EntryList])
+		report.add(manifest.EntryList, "", err, "entry list imported")
+	}
+
+	imported := 0
+	for _, race := range manifest.Races {
+		if s.importArchiveRace(seasonName, race, files, report) {
+			imported++
+		}
+	}
+
+	if imported == 0 {
+		report.add("", "", fmt.Errorf("no race could be imported, season %v was removed", seasonName), "")
+		report.Removed = true
+		if err := s.RemoveSeason(seasonName); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.Info("imported season archive", "season", seasonName, "races", imported, "files", len(report.Lines), "failed", report.Failed())
+
+	return report, nil
+}
+
+// importArchiveRace add the race with its results, false if the race was not imported
+func (s *RaceData) importArchiveRace(seasonName string, race ArchiveRace, files map[string][]byte, report *ImportReport) bool {
+	qualyFile := path.Join(race.Dir, race.Qualy)
+	raceFile := path.Join(race.Dir, race.Race)
+
+	qualyResult, qualyFound := files[qualyFile]
+	raceResult, raceFound := files[raceFile]
+	if race.Qualy == "" || !qualyFound {
+		report.add(qualyFile, race.Name, fmt.Errorf("qualy result not found"), "")
+		return false
+	}
+	if race.Race == "" || !raceFound {
+		report.add(raceFile, race.Name, fmt.Errorf("race result not found"), "")
+		return false
+	}
+
+	if err := s.AddRace(seasonName, race.Name); err != nil {
+		report.add(race.Dir, race.Name, err, "")
+		return false
+	}
+
+	if err := s.ImportResults(seasonName, race.Name, race.Format, qualyResult, raceResult); err != nil {
+		report.add(raceFile, race.Name, err, "")
+		s.RemoveRace(seasonName, race.Name)
+		return false
+	}
+	report.add(qualyFile, race.Name, nil, "qualy result imported")
+	report.add(raceFile, race.Name, nil, "race result imported")
+
+	for _, p := range race.Penalties {
+		err := s.AddPenalty(seasonName, race.Name, p.Session, p.Penalty, p.Pos)
+		report.add(ARCHIVE_MANIFEST, race.Name, err, fmt.Sprintf("penalty %vs for pos %v", p.Penalty, p.Pos))
+	}
+	return true
+}
+
+// readArchiveFiles read all files of the archive, a single top level
+// folder wrapping everything is removed from the file names, the size
+// limits apply to the bytes read and not only to the zip headers
+func readArchiveFiles(zr *zip.Reader) (map[string][]byte, error) {
+	if len(zr.File) > MAX_ARCHIVE_ENTRIES {
+		return nil, fmt.Errorf("season archive has more than %v entries", MAX_ARCHIVE_ENTRIES)
+	}
+
+	names := []string{}
+	for _, f := range zr.File {
+		if skipArchiveFile(f) {
+			continue
+		}
+		names = append(names, f.Name)
+	}
+	prefix := commonArchivePrefix(names)
+
+	files := map[string][]byte{}
+	total := 0
+	for _, f := range zr.File {
+		if skipArchiveFile(f) {
+			continue
+		}
+		if f.UncompressedSize64 > MAX_ARCHIVE_FILE_SIZE {
+			return nil, fmt.Errorf("file %v in season archive is too big", f.Name)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(io.LimitReader(rc, MAX_ARCHIVE_FILE_SIZE+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("can not read %v from season archive - %v", f.Name, err)
+		}
+		if len(b) > MAX_ARCHIVE_FILE_SIZE {
+			return nil, fmt.Errorf("file %v in season archive is too big", f.Name)
+		}
+		total += len(b)
+		if total > MAX_ARCHIVE_SIZE {
+			return nil, fmt.Errorf("season archive is bigger than %v bytes uncompressed", MAX_ARCHIVE_SIZE)
+		}
+		files[strings.TrimPrefix(f.Name, prefix)] = b
+	}
+	return files, nil
+}
+
+// skipArchiveFile folders, hidden files and macOS resource forks
+func skipArchiveFile(f *zip.File) bool {
+	return f.FileInfo().IsDir() ||
+		strings.HasPrefix(path.Base(f.Name), ".") ||
+		strings.HasPrefix(f.Name, "__MACOSX/")
+}
+
+// commonArchivePrefix top level folder shared by all files, only if it
+// contains race folders itself so a single race folder is not removed
+func commonArchivePrefix(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	i := strings.Index(names[0], "/")
+	if i == -1 {
+		return ""
+	}
+	prefix := names[0][:i+1]
+	nested := false
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			return ""
+		}
+		if strings.Contains(strings.TrimPrefix(name, prefix), "/") {
+			nested = true
+		}
+	}
+	if !nested {
+		return ""
+	}
+	return prefix
+}
+
+// archiveManifest read the manifest or derive it from the folder structure,
+// races without manifest are ordered by folder name
+func archiveManifest(files map[string][]byte) (*ArchiveManifest, error) {
+	manifest := &ArchiveManifest{}
+	if b, found := files[ARCHIVE_MANIFEST]; found {
+		if err := json.Unmarshal(b, manifest); err != nil {
+			return nil, fmt.Errorf("can not parse %v - %v", ARCHIVE_MANIFEST, err)
+		}
+	}
+
+	dirs := map[string][]string{}
+	rootFiles := []string{}
+	for name := range files {
+		dir, file := path.Split(name)
+		if dir == "" {
+			rootFiles = append(rootFiles, file)
+			continue
+		}
+		dir = strings.TrimSuffix(dir, "/")
+		dirs[dir] = append(dirs[dir], file)
+	}
+
+	if manifest.EntryList == "" {
+		manifest.EntryList = findEntryList(rootFiles)
+	}
+
+	if len(manifest.Races) == 0 {
+		for dir := range dirs {
+			manifest.Races = append(manifest.Races, ArchiveRace{Dir: dir})
+		}
+		sort.Slice(manifest.Races, func(i, j int) bool {
+			return manifest.Races[i].Dir < manifest.Races[j].Dir
+		})
+	}
+
+	for i := range manifest.Races {
+		race := &manifest.Races[i]
+		if race.Dir == "" {
+			race.Dir = race.Name
+		}
+		if race.Name == "" {
+			race.Name = race.Dir
+		}
+		// sorted so the first matching file wins independent of the zip order
+		sort.Strings(dirs[race.Dir])
+		for _, file := range dirs[race.Dir] {
+			lower := strings.ToLower(file)
+			if race.Qualy == "" && strings.Contains(lower, "qual") {
+				race.Qualy = file
+			}
+			if race.Race == "" && strings.Contains(lower, "race") && !strings.Contains(lower, "qual") {
+				race.Race = file
+			}
+		}
+		if race.Format == "" {
+			race.Format = formatFromExtension(race.Race)
+		}
+	}
+
+	return manifest, nil
+}
+
+func findEntryList(rootFiles []string) string {
+	csvFiles := []string{}
+	for _, file := range rootFiles {
+		lower := strings.ToLower(file)
+		if !strings.HasSuffix(lower, ".csv") {
+			continue
+		}
+		if strings.Contains(lower, "entry") || strings.Contains(lower, "enty") {
+			return file
+		}
+		csvFiles = append(csvFiles, file)
+	}
+	if len(csvFiles) == 1 {
+		return csvFiles[0]
+	}
+	return ""
+}
+
+func formatFromExtension(filename string) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".json":
+		return AC_FORMAT
+	case ".xml":
+		return RF2_FORMAT
+	}
+	return SGP_FORMAT
+}
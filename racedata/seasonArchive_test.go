@@ -0,0 +1,229 @@
+package racedata
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testArchiveResult = `pos,startPos,participant,class,totalTime,bestLapTime,laps
+1,2,Team A,GT3,3600000,100000,30
+2,1,Team B,GT3,3610000,101000,30
+`
+
+// testArchive zip archive with one entry per file, folders are
+// created for names ending with a slash
+func testArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// reportLines report lines as "file race ok|failed" for comparison
+func reportLines(report *ImportReport) map[string]bool {
+	lines := map[string]bool{}
+	for _, line := range report.Lines {
+		lines[line.File+" "+line.Race] = line.Ok
+	}
+	return lines
+}
+
+func TestImportSeasonArchive(t *testing.T) {
+	rd := newTestRaceData(t)
+
+	archive := testArchive(t, map[string]string{
+		"season/entry_list.csv":       testEntryList,
+		"season/round 1/qualy.csv":    testArchiveResult,
+		"season/round 1/race.csv":     testArchiveResult,
+		"season/round 2/qualy.csv":    testArchiveResult,
+		"season/round 2/race.csv":     "pos;team\n1;Team A\n",
+		"season/round 3/race.csv":     testArchiveResult,
+		"season/.DS_Store":            "",
+		"__MACOSX/season/._race.csv":  "",
+		"season/round 1/notes.txt":    "ignored",
+		"season/round 1/analysis/":    "",
+		"season/round 1/race_old.bak": "",
+	})
+
+	report, err := rd.ImportSeasonArchive("Season 2", archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]bool{
+		"entry_list.csv ":           true,
+		"round 1/qualy.csv round 1": true,
+		"round 1/race.csv round 1":  true,
+		"round 2/race.csv round 2":  false,
+		"round 3 round 3":           false,
+	}
+	got := reportLines(report)
+	for line, ok := range want {
+		if got[line] != ok {
+			t.Errorf("report line %q ok %v, want %v: %+v", line, got[line], ok, report.Lines)
+		}
+	}
+	if report.Failed() != 2 || report.Removed {
+		t.Errorf("%v failed, removed %v, want 2 failed files of a kept season", report.Failed(), report.Removed)
+	}
+
+	season := rd.Seasons["Season 2"]
+	if len(season.Races) != 1 || season.Races[0].Name != "round 1" {
+		t.Fatalf("unexpected races %+v", season.Races)
+	}
+	raceResult, err := rd.GetRaceResult("Season 2", "round 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := raceResult.RaceResultWithPenalty["GT3"]; len(lines) != 2 || lines[0].Driver != "Anna Fast" {
+		t.Errorf("unexpected race result %+v", lines)
+	}
+}
+
+func TestImportSeasonArchiveManifest(t *testing.T) {
+	rd := newTestRaceData(t)
+
+	archive := testArchive(t, map[string]string{
+		"drivers.csv": testEntryList,
+		"manifest.json": `{"entry_list":"drivers.csv","races":[
+			{"name":"Monza","dir":"r2","penalties":[{"pos":"1","penalty":"30"},{"pos":"9","penalty":"5"}]},
+			{"name":"Spa","dir":"r1"}]}`,
+		"r1/qualifying.csv": testArchiveResult,
+		"r1/race.csv":       testArchiveResult,
+		"r2/qualifying.csv": testArchiveResult,
+		"r2/race.csv":       testArchiveResult,
+	})
+
+	report, err := rd.ImportSeasonArchive("Season 2", archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Failed() != 1 {
+		t.Errorf("%v failed, want only the penalty of the missing pos: %+v", report.Failed(), report.Lines)
+	}
+
+	races := rd.Seasons["Season 2"].Races
+	if len(races) != 2 || races[0].Name != "Monza" || races[1].Name != "Spa" {
+		t.Fatalf("races %+v, want manifest order", races)
+	}
+	raceResult, err := rd.GetRaceResult("Season 2", "Monza")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := raceResult.RaceResultWithPenalty["GT3"]; lines[0].Team != "Team B" || lines[1].Penalty != "30" {
+		t.Errorf("penalty of the manifest not applied %+v", lines)
+	}
+}
+
+func TestImportSeasonArchiveNoRace(t *testing.T) {
+	rd := newTestRaceData(t)
+
+	archive := testArchive(t, map[string]string{
+		"entry_list.csv":    testEntryList,
+		"round 1/race.csv":  "pos;team\n1;Team A\n",
+		"round 1/qualy.csv": testArchiveResult,
+	})
+
+	report, err := rd.ImportSeasonArchive("Season 2", archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Removed {
+		t.Errorf("season without races was kept: %+v", report.Lines)
+	}
+	if _, found := rd.Seasons["Season 2"]; found {
+		t.Error("season was not removed")
+	}
+}
+
+func TestImportSeasonArchiveLimits(t *testing.T) {
+	entries := map[string]string{}
+	for i := 0; i <= MAX_ARCHIVE_ENTRIES; i++ {
+		entries[fmt.Sprintf("round %v/", i)] = ""
+	}
+
+	for _, tc := range []struct {
+		name    string
+		archive func(t *testing.T) []byte
+		err     string
+	}{
+		{"not a zip", func(t *testing.T) []byte { return []byte("entry list") }, "can not open"},
+		{"oversized file", func(t *testing.T) []byte {
+			return testArchive(t, map[string]string{
+				"entry_list.csv":   testEntryList,
+				"round 1/race.csv": strings.Repeat("0", MAX_ARCHIVE_FILE_SIZE+1),
+			})
+		}, "too big"},
+		// the zip reader stops at the size of the header
+		{"oversized file with a wrong header", func(t *testing.T) []byte {
+			return oversizedRawArchive(t)
+		}, "can not read round 1/race.csv"},
+		{"too many entries", func(t *testing.T) []byte { return testArchive(t, entries) }, "entries"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rd := newTestRaceData(t)
+			_, err := rd.ImportSeasonArchive("Season 2", tc.archive(t))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error %v, want %q", err, tc.err)
+			}
+			if _, found := rd.Seasons["Season 2"]; found {
+				t.Error("season of a rejected archive was created")
+			}
+		})
+	}
+}
+
+// oversizedRawArchive archive with a file whose header claims a small
+// uncompressed size while the data is bigger than the file size limit
+func oversizedRawArchive(t *testing.T) []byte {
+	t.Helper()
+	compressed := &bytes.Buffer{}
+	zw := zip.NewWriter(compressed)
+	w, err := zw.Create("race.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(bytes.Repeat([]byte("0"), MAX_ARCHIVE_FILE_SIZE+1))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(compressed.Bytes()), int64(compressed.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := zr.File[0].OpenRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	out := zip.NewWriter(buf)
+	header := zr.File[0].FileHeader
+	header.Name = "round 1/race.csv"
+	header.UncompressedSize64 = 100
+	rw, err := out.CreateRaw(&header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(rw, raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,211 @@
+package racedata
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// MAX_SLUG_LENGTH max length of season and race slugs
+const MAX_SLUG_LENGTH = 64
+
+// transliterations ascii replacement of latin letters with diacritics
+var transliterations = func() map[rune]string {
+	m := map[rune]string{}
+	for from, to := range map[string]string{
+		"àáâãāăą": "a", "çćĉċč": "c", "ďđ": "d", "èéêëēĕėęě": "e",
+		"ĝğġģ": "g", "ĥħ": "h", "ìíîïĩīĭįı": "i", "ĵ": "j", "ķ": "k",
+		"ĺļľŀł": "l", "ñńņňŉ": "n", "òóôõōŏő": "o", "ŕŗř": "r",
+		"śŝşšș": "s", "ţťŧț": "t", "ùúûũūŭůűų": "u", "ŵ": "w",
+		"ýÿŷ": "y", "źżž": "z",
+		"ä": "ae", "ö": "oe", "ü": "ue", "ß": "ss", "æ": "ae", "ø": "o",
+		"å": "a", "œ": "oe", "þ": "th", "ð": "d",
+	} {
+		for _, r := range from {
+			m[r] = to
+		}
+	}
+	return m
+}()
+
+// Slugify url and file system safe name, only a-z, 0-9 and single dashes,
+// umlauts and accents are transliterated, other characters are dropped
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	write := func(s string) {
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		dash = false
+		b.WriteString(s)
+	}
+
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z' || r >= '0' && r <= '9':
+			write(string(r))
+		case transliterations[r] != "":
+			write(transliterations[r])
+		default:
+			dash = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > MAX_SLUG_LENGTH {
+		slug = strings.TrimRight(slug[:MAX_SLUG_LENGTH], "-")
+	}
+	return slug
+}
+
+// uniqueSlug slug of the name, numbered if already taken,
+// fallback is used for names without any usable character
+func uniqueSlug(name string, fallback string, taken func(string) bool) string {
+	base := Slugify(name)
+	if base == "" {
+		base = fallback
+	}
+	slug := base
+	for i := 2; taken(slug); i++ {
+		slug = fmt.Sprintf("%v-%v", base, i)
+	}
+	return slug
+}
+
+// seasonSlug unique slug for the season, also checked against existing
+// directories, e.g. of removed seasons. The current slug of the season
+// is not a collision
+func (s *RaceData) seasonSlug(name string, current string) string {
+	return uniqueSlug(name, "season", func(slug string) bool {
+		if slug == current {
+			return false
+		}
+		for _, season := range s.Seasons {
+			if season.Slug == slug {
+				return true
+			}
+		}
+		_, err := os.Stat(path.Join(s.DataDir, slug))
+		return err == nil
+	})
+}
+
+// raceSlug unique slug for a race of the season
+func (s *RaceData) raceSlug(season Season, name string, current string) string {
+	return uniqueSlug(name, "race", func(slug string) bool {
+		if slug == current {
+			return false
+		}
+		for _, race := range season.Races {
+			if race.Slug == slug {
+				return true
+			}
+		}
+		_, err := os.Stat(path.Join(s.DataDir, season.Slug, slug))
+		return err == nil
+	})
+}
+
+// SeasonName name of the season with the given name or slug
+func (s *RaceData) SeasonName(key string) (string, bool) {
+	if _, found := s.Seasons[key]; found {
+		return key, true
+	}
+	for name, season := range s.Seasons {
+		if season.Slug == key {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// RaceName name of the race of the season with the given name or slug
+func (s *RaceData) RaceName(seasonName string, key string) (string, bool) {
+	season, found := s.Seasons[seasonName]
+	if !found {
+		return "", false
+	}
+	for _, race := range season.Races {
+		if race.Name == key {
+			return key, true
+		}
+	}
+	for _, race := range season.Races {
+		if race.Slug == key {
+			return race.Name, true
+		}
+	}
+	return "", false
+}
+
+// migrateSlugs add slugs to seasons and races of older race data files
+// and move their directories from the previous naming scheme
+func (s *RaceData) migrateSlugs() {
+	names := []string{}
+	for name := range s.Seasons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changed := false
+	for _, name := range names {
+		season := s.Seasons[name]
+		if season.Slug == "" {
+			oldDir := path.Join(s.DataDir, legacyDir(name))
+			season.Slug = s.seasonSlug(name, legacyDir(name))
+			if err := s.moveSeasonDir(&season, oldDir); err != nil {
+				s.logger.Error("can not move season directory", "dir", oldDir, "error", err)
+			}
+			changed = true
+		}
+		for i := range season.Races {
+			race := &season.Races[i]
+			if race.Slug != "" {
+				continue
+			}
+			oldDir := path.Join(s.DataDir, season.Slug, legacyDir(race.Name))
+			race.Slug = s.raceSlug(season, race.Name, legacyDir(race.Name))
+			newDir := s.raceDir(season, *race)
+			if err := moveDataDir(oldDir, newDir); err != nil {
+				s.logger.Error("can not move race directory", "dir", oldDir, "error", err)
+			}
+			race.QualyResultFile = replaceDir(race.QualyResultFile, oldDir, newDir)
+			race.RaceResultFile = replaceDir(race.RaceResultFile, oldDir, newDir)
+			race.LapDataFile = replaceDir(race.LapDataFile, oldDir, newDir)
+			changed = true
+		}
+		s.Seasons[name] = season
+	}
+
+	if changed {
+		s.logger.Info("added slugs to seasons and races")
+		s.writeSeasonsFile()
+	}
+}
+
+// moveSeasonDir move the season directory to its slug
+// and update the stored filenames
+func (s *RaceData) moveSeasonDir(season *Season, oldDir string) error {
+	newDir := s.seasonDir(*season)
+	if err := moveDataDir(oldDir, newDir); err != nil {
+		return err
+	}
+	season.EntyListFile = replaceDir(season.EntyListFile, oldDir, newDir)
+	for i := range season.Races {
+		season.Races[i].QualyResultFile = replaceDir(season.Races[i].QualyResultFile, oldDir, newDir)
+		season.Races[i].RaceResultFile = replaceDir(season.Races[i].RaceResultFile, oldDir, newDir)
+		season.Races[i].LapDataFile = replaceDir(season.Races[i].LapDataFile, oldDir, newDir)
+	}
+	if season.Branding != nil {
+		season.Branding.LogoFile = replaceDir(season.Branding.LogoFile, oldDir, newDir)
+	}
+	return nil
+}
+
+// legacyDir directory name used before slugs
+func legacyDir(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", "_"))
+}
@@ -0,0 +1,178 @@
+package racedata
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		slug string
+	}{
+		{"Season 1", "season-1"},
+		{"GT3 Sprint 2024", "gt3-sprint-2024"},
+		{"  Race #1 -- Monza!  ", "race-1-monza"},
+		{"Nürburgring Nordschleife", "nuerburgring-nordschleife"},
+		{"Großer Preis", "grosser-preis"},
+		{"Circuit de Spa-Francorchamps", "circuit-de-spa-francorchamps"},
+		{"Autódromo José Carlos Pace", "autodromo-jose-carlos-pace"},
+		{"Łódź", "lodz"},
+		{"../../etc", "etc"},
+		{"鈴鹿", ""},
+		{"---", ""},
+		{strings.Repeat("a", MAX_SLUG_LENGTH-1) + " b", strings.Repeat("a", MAX_SLUG_LENGTH-1)},
+		{strings.Repeat("ab", MAX_SLUG_LENGTH), strings.Repeat("ab", MAX_SLUG_LENGTH/2)},
+	} {
+		if slug := Slugify(tc.name); slug != tc.slug {
+			t.Errorf("Slugify(%q) = %q, want %q", tc.name, slug, tc.slug)
+		}
+	}
+}
+
+func TestUniqueSlug(t *testing.T) {
+	taken := map[string]bool{"monza": true, "monza-2": true, "race": true}
+	for _, tc := range []struct {
+		name string
+		slug string
+	}{
+		{"Spa", "spa"},
+		{"Monza", "monza-3"},
+		{"MONZA", "monza-3"},
+		{"鈴鹿", "race-2"},
+	} {
+		if slug := uniqueSlug(tc.name, "race", func(s string) bool { return taken[s] }); slug != tc.slug {
+			t.Errorf("uniqueSlug(%q) = %q, want %q", tc.name, slug, tc.slug)
+		}
+	}
+}
+
+func TestSlugCollisions(t *testing.T) {
+	rd := newTestRaceData(t)
+
+	for _, name := range []string{"Season 1!", "season_1", "鈴鹿", "鈴鹿 2"} {
+		if err := rd.AddSeason(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"Monza", "monza", "Monza?"} {
+		if err := rd.AddRace("Season 1", name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// directories of removed seasons are not reused
+	if err := os.MkdirAll(filepath.Join(rd.DataDir, "removed"), 0770); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.AddSeason("Removed"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"Season 1":  "season-1",
+		"Season 1!": "season-1-2",
+		"season_1":  "season-1-3",
+		"鈴鹿":        "season",
+		"鈴鹿 2":      "2",
+		"Removed":   "removed-2",
+	}
+	for name, slug := range want {
+		if got := rd.Seasons[name].Slug; got != slug {
+			t.Errorf("season %q slug %q, want %q", name, got, slug)
+		}
+	}
+
+	races := []string{}
+	for _, race := range rd.Seasons["Season 1"].Races {
+		races = append(races, race.Slug)
+	}
+	if got := strings.Join(races, ","); got != "race-1,monza,monza-2,monza-3" {
+		t.Errorf("race slugs %v", got)
+	}
+
+	for key, name := range map[string]string{"season-1-2": "Season 1!", "Season 1!": "Season 1!", "season-1": "Season 1"} {
+		if got, found := rd.SeasonName(key); !found || got != name {
+			t.Errorf("SeasonName(%q) = %q, want %q", key, got, name)
+		}
+	}
+	if got, found := rd.RaceName("Season 1", "monza-2"); !found || got != "monza" {
+		t.Errorf("RaceName(monza-2) = %q, want monza", got)
+	}
+}
+
+func TestMigrateSlugs(t *testing.T) {
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "data")
+	legacySeason := filepath.Join(dataDir, "gt3_sprint")
+	legacyRace := filepath.Join(legacySeason, "race_1")
+	if err := os.MkdirAll(legacyRace, 0770); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{
+		filepath.Join(legacySeason, "entrylist.csv"),
+		filepath.Join(legacyRace, "qualy_result.csv"),
+		filepath.Join(legacyRace, "race_result.csv"),
+	} {
+		if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// an existing directory with the slug of the season
+	if err := os.MkdirAll(filepath.Join(dataDir, "gt3-sprint"), 0770); err != nil {
+		t.Fatal(err)
+	}
+
+	seasons := SeasonMap{
+		"GT3 Sprint": {
+			EntyListFile: filepath.Join(legacySeason, "entrylist.csv"),
+			Races: []Race{
+				{Name: "Race 1", QualyResultFile: filepath.Join(legacyRace, "qualy_result.csv"), RaceResultFile: filepath.Join(legacyRace, "race_result.csv")},
+				{Name: "Race 2"},
+			},
+		},
+		"Empty": {Races: []Race{}},
+	}
+	b, err := json.Marshal(seasons)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raceDataFile := filepath.Join(dir, "race_data.json")
+	if err := os.WriteFile(raceDataFile, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rd := NewRaceData(dataDir, raceDataFile, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	season := rd.Seasons["GT3 Sprint"]
+	if season.Slug != "gt3-sprint-2" || season.Races[0].Slug != "race-1" || season.Races[1].Slug != "race-2" {
+		t.Fatalf("unexpected slugs %+v", season)
+	}
+	if rd.Seasons["Empty"].Slug != "empty" {
+		t.Errorf("slug of season without directory %q", rd.Seasons["Empty"].Slug)
+	}
+
+	newRace := filepath.Join(dataDir, "gt3-sprint-2", "race-1")
+	for _, file := range []string{season.EntyListFile, season.Races[0].QualyResultFile, season.Races[0].RaceResultFile} {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("moved file %v: %v", file, err)
+		}
+	}
+	if season.Races[0].RaceResultFile != filepath.Join(newRace, "race_result.csv") {
+		t.Errorf("race result %v, want it in %v", season.Races[0].RaceResultFile, newRace)
+	}
+	if _, err := os.Stat(legacySeason); !os.IsNotExist(err) {
+		t.Errorf("legacy directory was not moved: %v", err)
+	}
+
+	// the migration is stored and not repeated
+	reloaded := NewRaceData(dataDir, raceDataFile, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if got := reloaded.Seasons["GT3 Sprint"].Slug; got != "gt3-sprint-2" {
+		t.Errorf("slug after reload %q", got)
+	}
+}
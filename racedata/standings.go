@@ -0,0 +1,148 @@
+package racedata
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultPoints points for the finishing positions of a split
+var DefaultPoints = []int{25, 18, 15, 12, 10, 8, 6, 4, 2, 1}
+
+// PointsRule bonus points of a league for the pole position, the fastest
+// lap and the fastest clean lap of a split. The fastest lap points only
+// count for cars finishing at FastestLapMaxPos or better, 0 for every
+// classified car
+type PointsRule struct {
+	Pole             int
+	FastestLap       int
+	FastestLapMaxPos int
+	FastestCleanLap  int
+}
+
+// SetPoints bonus points of the standings and driver results
+func (s *RaceData) SetPoints(points PointsRule) {
+	s.points = points
+}
+
+// Standing championship position of a team in a split
+type Standing struct {
+	Pos     int    `json:"pos"`
+	Team    string `json:"team"`
+	Driver  string `json:"driver"`
+	Points  int    `json:"points"`
+	Races   int    `json:"races"`
+	Wins    int    `json:"wins"`
+	Podiums int    `json:"podiums"`
+	Poles   int    `json:"poles"`
+	// FastestLaps and FastestCleanLaps race splits with the fastest (clean) lap
+	FastestLaps      int `json:"fastest_laps"`
+	FastestCleanLaps int `json:"fastest_clean_laps"`
+}
+
+// Standings championship standings per split
+type Standings map[string][]Standing
+
+// GetStandings sum up the points of all races with results, based on the
+// result with penalties, cars without a lap get no points. The pole
+// position, the fastest lap and the fastest clean lap of every split
+// get the bonus points of the points rule
+func (s *RaceData) GetStandings(seasonName string) (Standings, error) {
+	season, found := s.Seasons[seasonName]
+	if !found {
+		return nil, fmt.Errorf("season %v %w", seasonName, ErrNotFound)
+	}
+
+	teams := map[string]map[string]*Standing{}
+	for _, race := range season.Races {
+		if race.RaceResultFile == "" {
+			continue
+		}
+		raceResult, err := s.GetRaceResult(seasonName, race.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		for split, lines := range raceResult.RaceResultWithPenalty {
+			if teams[split] == nil {
+				teams[split] = map[string]*Standing{}
+			}
+			pos := 0
+			for _, line := range lines {
+				if line.Laps == "0" {
+					continue
+				}
+				standing, found := teams[split][line.Team]
+				if !found {
+					standing = &Standing{Team: line.Team, Driver: line.Driver}
+					teams[split][line.Team] = standing
+				}
+				standing.Races++
+				standing.Points += s.points.resultPoints(line, pos)
+				if pos == 0 {
+					standing.Wins++
+				}
+				if pos < 3 {
+					standing.Podiums++
+				}
+				if line.FastestLap {
+					standing.FastestLaps++
+				}
+				if line.FastestCleanLap {
+					standing.FastestCleanLaps++
+				}
+				pos++
+			}
+		}
+
+		for split, pole := range raceResult.Pole {
+			if teams[split] == nil {
+				teams[split] = map[string]*Standing{}
+			}
+			standing, found := teams[split][pole.Team]
+			if !found {
+				standing = &Standing{Team: pole.Team, Driver: pole.Driver}
+				teams[split][pole.Team] = standing
+			}
+			standing.Poles++
+			standing.Points += s.points.Pole
+		}
+	}
+
+	standings := Standings{}
+	for split, byTeam := range teams {
+		list := []Standing{}
+		for _, standing := range byTeam {
+			list = append(list, *standing)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Points != list[j].Points {
+				return list[i].Points > list[j].Points
+			}
+			if list[i].Wins != list[j].Wins {
+				return list[i].Wins > list[j].Wins
+			}
+			return list[i].Team < list[j].Team
+		})
+		for i := range list {
+			list[i].Pos = i + 1
+		}
+		standings[split] = list
+	}
+	return standings, nil
+}
+
+// resultPoints points of a result line at the zero based position of the
+// cars with a lap, including the fastest lap bonus points
+func (p PointsRule) resultPoints(line ResultLine, pos int) int {
+	points := 0
+	if pos < len(DefaultPoints) {
+		points += DefaultPoints[pos]
+	}
+	if line.FastestLap && (p.FastestLapMaxPos == 0 || pos < p.FastestLapMaxPos) {
+		points += p.FastestLap
+	}
+	if line.FastestCleanLap {
+		points += p.FastestCleanLap
+	}
+	return points
+}
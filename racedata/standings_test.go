@@ -0,0 +1,33 @@
+package racedata
+
+import "testing"
+
+func TestStandingsPointsRule(t *testing.T) {
+	result := []byte(`pos,participant,class,totalTime,bestLapTime,laps
+1,Team A,GT3,3600000,100000,30
+2,Team B,GT3,3610000,101000,30
+`)
+	points := func(rule PointsRule) map[string]int {
+		rd := newTestRaceData(t)
+		rd.SetPoints(rule)
+		if err := rd.AddResults("Season 1", "Race 1", result, result); err != nil {
+			t.Fatal(err)
+		}
+		standings, err := rd.GetStandings("Season 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		byTeam := map[string]int{}
+		for _, standing := range standings["GT3"] {
+			byTeam[standing.Team] = standing.Points
+		}
+		return byTeam
+	}
+
+	if got := points(PointsRule{}); got["Team A"] != 25 || got["Team B"] != 18 {
+		t.Errorf("unexpected points without bonus %v", got)
+	}
+	if got := points(PointsRule{Pole: 3, FastestLap: 2}); got["Team A"] != 30 || got["Team B"] != 18 {
+		t.Errorf("unexpected points with bonus %v", got)
+	}
+}
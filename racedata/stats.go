@@ -0,0 +1,95 @@
+package racedata
+
+import (
+	"os"
+	"time"
+)
+
+// Stats counts of the race data for monitoring
+type Stats struct {
+	Seasons   int
+	Races     int
+	Results   int
+	Penalties int
+}
+
+// penaltyCount penalties of a race result file, valid as long as
+// the file keeps its modification time and size
+type penaltyCount struct {
+	modTime   time.Time
+	size      int64
+	penalties int
+}
+
+// GetStats count seasons, races, races with results and
+// race result lines with a penalty. Result files are only
+// read again after they changed
+func (s *RaceData) GetStats() Stats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	counts := map[string]penaltyCount{}
+	stats := Stats{Seasons: len(s.Seasons)}
+	for _, season := range s.Seasons {
+		stats.Races += len(season.Races)
+		for _, race := range season.Races {
+			if race.RaceResultFile == "" {
+				continue
+			}
+			stats.Results++
+			count, err := s.penaltyCount(race.RaceResultFile)
+			if err != nil {
+				continue
+			}
+			counts[race.RaceResultFile] = count
+			stats.Penalties += count.penalties
+		}
+	}
+	s.penaltyCounts = counts
+	return stats
+}
+
+// penaltyCount cached penalties of the result file, the file is
+// read if it changed since the last count
+func (s *RaceData) penaltyCount(filename string) (penaltyCount, error) {
+	info, err := os.Stat(filename)
+	if err != nil {
+		return penaltyCount{}, err
+	}
+	count, found := s.penaltyCounts[filename]
+	if found && count.modTime.Equal(info.ModTime()) && count.size == info.Size() {
+		return count, nil
+	}
+
+	result, err := readResult(filename)
+	if err != nil {
+		return penaltyCount{}, err
+	}
+	count = penaltyCount{modTime: info.ModTime(), size: info.Size()}
+	for _, line := range *result {
+		if line.Penalty != "" && line.Penalty != "0" {
+			count.penalties++
+		}
+	}
+	return count, nil
+}
+
+// CheckWritable create and remove a file in the data directory
+func (s *RaceData) CheckWritable() error {
+	if err := os.MkdirAll(s.DataDir, 0770); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.DataDir, ".healthcheck-*")
+	if err != nil {
+		return err
+	}
+	name := f.Name()
+	_, err = f.WriteString("ok")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+package racedata
+
+import "testing"
+
+func TestGetStatsPenalties(t *testing.T) {
+	rd := newTestRaceData(t)
+	result := []byte(`pos,participant,class,totalTime,bestLapTime,laps,penalty
+1,Team A,GT3,3600000,100000,30,0
+2,Team B,GT3,3610000,101000,30,5
+`)
+	if err := rd.AddResults("Season 1", "Race 1", result, result); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := rd.GetStats()
+	if stats != (Stats{Seasons: 1, Races: 1, Results: 1, Penalties: 1}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats := rd.GetStats(); stats.Penalties != 1 {
+		t.Errorf("cached penalties %v", stats.Penalties)
+	}
+
+	if err := rd.AddPenalty("Season 1", "Race 1", SESSION_RACE, "10", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if stats := rd.GetStats(); stats.Penalties != 2 {
+		t.Errorf("penalty not counted after the result changed, penalties %v", stats.Penalties)
+	}
+}
@@ -0,0 +1,239 @@
+package racedata
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// TEAM_REGISTRY_FILE team ids of all seasons, stored next to the race data file
+const TEAM_REGISTRY_FILE = "teams.json"
+
+// RegisteredTeam url id of a team name, ids are unique slugs of the
+// name and do not change once assigned
+type RegisteredTeam struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// TeamStanding championship position of the team in a split of a season
+type TeamStanding struct {
+	Split  string `json:"split"`
+	Pos    int    `json:"pos"`
+	Points int    `json:"points"`
+	Teams  int    `json:"teams"`
+}
+
+// TeamSeason cars and drivers of the team in the entry list of a season
+// and its championship positions
+type TeamSeason struct {
+	Season     string         `json:"season"`
+	SeasonSlug string         `json:"season_slug"`
+	Entries    []Driver       `json:"entries"`
+	Standings  []TeamStanding `json:"standings"`
+}
+
+// TeamProfile career of a team over all seasons, LeaderSplits counts
+// the season splits where the team is first in the current standings,
+// running seasons included
+type TeamProfile struct {
+	ID   string `json:"id"`
+	Team string `json:"team"`
+	CareerStats
+	LeaderSplits int            `json:"leader_splits"`
+	Seasons      []TeamSeason   `json:"seasons"`
+	Results      []DriverResult `json:"results"`
+}
+
+// teamID registered id of the team name, empty for unknown teams
+func (s *RaceData) teamID(team string) string {
+	team = strings.TrimSpace(team)
+	for _, t := range s.teams {
+		if t.Name == team {
+			return t.ID
+		}
+	}
+	return ""
+}
+
+// registerTeams add unknown team names to the registry and write it
+func (s *RaceData) registerTeams(teams []string) {
+	registered := len(s.teams)
+	for _, team := range teams {
+		team = strings.TrimSpace(team)
+		if team == "" || s.teamID(team) != "" {
+			continue
+		}
+		id := uniqueSlug(team, "team", func(slug string) bool {
+			for _, t := range s.teams {
+				if t.ID == slug {
+					return true
+				}
+			}
+			return false
+		})
+		s.teams = append(s.teams, RegisteredTeam{ID: id, Name: team})
+	}
+	if len(s.teams) > registered {
+		s.logger.Info("registered teams", "teams", len(s.teams)-registered)
+		s.writeTeamRegistry()
+	}
+}
+
+// addTeamIDs set the team id of every line of the race result
+func (s *RaceData) addTeamIDs(raceResult *RaceResult) {
+	for _, result := range []map[string]ResultLines{raceResult.QualiyResult, raceResult.RaceResult, raceResult.RaceResultWithPenalty} {
+		for _, lines := range result {
+			for i := range lines {
+				lines[i].TeamID = s.teamID(lines[i].Team)
+			}
+		}
+	}
+	for _, lines := range []map[string]ResultLine{raceResult.Pole, raceResult.FastestLap, raceResult.FastestCleanLap} {
+		for split, line := range lines {
+			line.TeamID = s.teamID(line.Team)
+			lines[split] = line
+		}
+	}
+}
+
+// GetTeamProfile entries, championship positions and race results of the
+// team in all seasons, seasons sorted by name and races in season order
+func (s *RaceData) GetTeamProfile(id string) (*TeamProfile, error) {
+	if id == "" {
+		return nil, fmt.Errorf("team %v %w", id, ErrNotFound)
+	}
+	profile := &TeamProfile{ID: id, Seasons: []TeamSeason{}}
+
+	for _, seasonName := range s.sortedSeasonNames() {
+		teamSeason := TeamSeason{
+			Season:     seasonName,
+			SeasonSlug: s.Seasons[seasonName].Slug,
+			Entries:    []Driver{},
+			Standings:  []TeamStanding{},
+		}
+
+		entryList, err := s.GetSeasonEntryList(seasonName)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range *entryList {
+			if entry.TeamID == id {
+				teamSeason.Entries = append(teamSeason.Entries, entry)
+				profile.Team = entry.Team
+			}
+		}
+
+		standings, err := s.GetStandings(seasonName)
+		if err != nil {
+			return nil, err
+		}
+		for _, split := range sortedStandingKeys(standings) {
+			for _, standing := range standings[split] {
+				if s.teamID(standing.Team) != id {
+					continue
+				}
+				teamSeason.Standings = append(teamSeason.Standings, TeamStanding{
+					Split:  split,
+					Pos:    standing.Pos,
+					Points: standing.Points,
+					Teams:  len(standings[split]),
+				})
+				if standing.Pos == 1 {
+					profile.LeaderSplits++
+				}
+			}
+		}
+
+		if len(teamSeason.Entries) > 0 || len(teamSeason.Standings) > 0 {
+			profile.Seasons = append(profile.Seasons, teamSeason)
+		}
+	}
+
+	results, err := s.careerResults(func(line ResultLine) bool {
+		return line.TeamID == id
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(profile.Seasons) == 0 && len(results) == 0 {
+		return nil, fmt.Errorf("team %v %w", id, ErrNotFound)
+	}
+	if profile.Team == "" && len(results) > 0 {
+		profile.Team = results[len(results)-1].Team
+	}
+	profile.Results = results
+	profile.CareerStats = careerStats(results)
+	return profile, nil
+}
+
+// sortedStandingKeys split names of the standings in alphabetical order
+func sortedStandingKeys(standings Standings) []string {
+	keys := make([]string, 0, len(standings))
+	for k := range standings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// migrateTeamRegistry register the teams of entry lists and results
+// added before the registry, in season order so ids are stable
+func (s *RaceData) migrateTeamRegistry() {
+	teams := []string{}
+	for _, seasonName := range s.sortedSeasonNames() {
+		season := s.Seasons[seasonName]
+		if season.EntyListFile != "" && s.checkDataFile(season.EntyListFile) == nil {
+			if entryList, err := readEntryList(season.EntyListFile); err == nil {
+				for _, line := range *entryList {
+					teams = append(teams, line.Team)
+				}
+			}
+		}
+		for _, race := range season.Races {
+			for _, filename := range []string{race.QualyResultFile, race.RaceResultFile} {
+				if filename == "" || s.checkDataFile(filename) != nil {
+					continue
+				}
+				if result, err := readResult(filename); err == nil {
+					for _, line := range *result {
+						teams = append(teams, line.Participant)
+					}
+				}
+			}
+		}
+	}
+	s.registerTeams(teams)
+}
+
+func (s *RaceData) teamRegistryFile() string {
+	return path.Join(path.Dir(s.RaceDataFile), TEAM_REGISTRY_FILE)
+}
+
+func (s *RaceData) readTeamRegistry() {
+	b, err := os.ReadFile(s.teamRegistryFile())
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		fatal(s.logger, "can not read team registry", err)
+	}
+	if err := json.Unmarshal(b, &s.teams); err != nil {
+		fatal(s.logger, "can not decode team registry", err)
+	}
+}
+
+func (s *RaceData) writeTeamRegistry() {
+	b, err := json.MarshalIndent(s.teams, "", "   ")
+	if err != nil {
+		fatal(s.logger, "can not encode team registry", err)
+	}
+	if err := os.WriteFile(s.teamRegistryFile(), b, 0644); err != nil {
+		fatal(s.logger, "can not write team registry", err)
+	}
+}
@@ -0,0 +1,45 @@
+package racedata
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+func TestTeamIDs(t *testing.T) {
+	rd := newTestRaceData(t)
+	entryList := "driver,team\nAnna Fast,Team A\nBen Slow,Team-A\nCarl Late,東京\nDora Quick,大阪\n"
+	if err := rd.AddEntryList("Season 1", "", []byte(entryList)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"Team A": "team-a", "Team-A": "team-a-2", "東京": "team", "大阪": "team-2"}
+	for team, id := range want {
+		if got := rd.teamID(team); got != id {
+			t.Errorf("team %q: id %q, want %q", team, got, id)
+		}
+	}
+
+	entries, err := rd.GetSeasonEntryList("Season 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range *entries {
+		profile, err := rd.GetTeamProfile(entry.TeamID)
+		if err != nil {
+			t.Fatalf("team %q: %v", entry.Team, err)
+		}
+		if profile.Team != entry.Team || len(profile.Seasons) != 1 || len(profile.Seasons[0].Entries) != 1 {
+			t.Errorf("team %q: unexpected profile %+v", entry.Team, profile)
+		}
+	}
+
+	// ids are kept after a restart, also if the first team is gone
+	if err := rd.AddEntryList("Season 1", "", []byte("driver,team\nBen Slow,Team-A\n")); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := NewRaceData(rd.DataDir, rd.RaceDataFile, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if got := reloaded.teamID("Team-A"); got != "team-a-2" {
+		t.Errorf("team id after reload %q, want team-a-2", got)
+	}
+}
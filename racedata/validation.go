@@ -0,0 +1,110 @@
+package racedata
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// ValidationReport result of the checks run on uploaded qualy and race results
+type ValidationReport struct {
+	Season   string              `json:"season"`
+	Race     string              `json:"race"`
+	Format   string              `json:"format"`
+	Ok       bool                `json:"ok"`
+	Sessions []SessionValidation `json:"sessions"`
+}
+
+type SessionValidation struct {
+	Session string   `json:"session"`
+	Ok      bool     `json:"ok"`
+	Lines   int      `json:"lines"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// ValidateResults run the checks of ImportResults on qualy and race result
+// files of the given format without storing anything
+func ValidateResults(format string, qualyResult []byte, raceResult []byte) *ValidationReport {
+	if format == "" {
+		format = SGP_FORMAT
+	}
+	report := &ValidationReport{Format: format, Ok: true}
+
+	for _, session := range []struct {
+		name string
+		data []byte
+	}{{"qualy", qualyResult}, {"race", raceResult}} {
+		v := SessionValidation{Session: session.name, Ok: true}
+		result, err := validateResult(format, session.data)
+		if err != nil {
+			v.Ok = false
+			v.Errors = append(v.Errors, err.Error())
+			report.Ok = false
+		} else {
+			v.Lines = len(*result)
+		}
+		report.Sessions = append(report.Sessions, v)
+	}
+	return report
+}
+
+// Error first error of the report
+func (r *ValidationReport) Error() error {
+	for _, s := range r.Sessions {
+		if !s.Ok {
+			return fmt.Errorf("%v result - %v", s.Session, s.Errors[0])
+		}
+	}
+	return nil
+}
+
+func validateResult(format string, data []byte) (*CSVResult, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("result is empty")
+	}
+
+	importer, err := GetImporter(format)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := importer.Import(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(*result) == 0 {
+		return nil, fmt.Errorf("result contains no lines")
+	}
+
+	if err := checkTeamNamesUnique(result); err != nil {
+		return nil, err
+	}
+	if err := checkResultNumbers(result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// checkResultNumbers times, lap counts and penalties must be empty or
+// integers, toRaceResult calculates with them
+func checkResultNumbers(result *CSVResult) error {
+	for _, line := range *result {
+		for _, field := range []struct {
+			name  string
+			value string
+		}{
+			{"totalTime", line.TotalTime},
+			{"bestLapTime", line.BestLapTime},
+			{"laps", line.Laps},
+			{"penalty", line.Penalty},
+		} {
+			if field.value == "" {
+				continue
+			}
+			if _, err := strconv.Atoi(field.value); err != nil {
+				return fmt.Errorf("pos %v: %v %q is not a number", line.Pos, field.name, field.value)
+			}
+		}
+	}
+	return nil
+}
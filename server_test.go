@@ -0,0 +1,116 @@
+package sgphelper
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sgpHelper/racedata"
+)
+
+const testCSRFToken = "test-csrf-token"
+
+const testEntryList = `driver,team,car,race_number,class
+Anna Fast,Team A,Porsche 911 GT3 R,1,GT3
+Ben Slow,Team B,Ferrari 296 GT3,2,GT3
+`
+
+const testResult = `pos,startPos,participant,car,class,totalTime,bestLapTime,bestCleanLapTime,laps
+1,2,Team A,Porsche 911 GT3 R,GT3,3600000,100000,100500,30
+2,1,Team B,Ferrari 296 GT3,GT3,3610000,101000,101200,30
+`
+
+const testLaps = `participant,lap,lapTime
+Team A,1,100000
+Team B,1,101000
+`
+
+// testServer server with one season, one race with results and
+// an entry list in a temporary data directory
+type testServer struct {
+	*Server
+	handler http.Handler
+	dir     string
+	data    *racedata.RaceData
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	rd := racedata.NewRaceData(filepath.Join(dir, "data"), filepath.Join(dir, "race_data.json"), logger)
+
+	if err := rd.AddSeason("Season 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.AddEntryList("Season 1", "", []byte(testEntryList)); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.AddRace("Season 1", "Race 1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rd.AddResults("Season 1", "Race 1", []byte(testResult), []byte(testResult)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(":0", rd)
+	s.SetLogger(logger)
+	return &testServer{Server: s, dir: dir, data: rd}
+}
+
+// serve send the request through all middlewares of the server
+func (ts *testServer) serve(r *http.Request) *httptest.ResponseRecorder {
+	if ts.handler == nil {
+		ts.handler = ts.Server.handler()
+	}
+	w := httptest.NewRecorder()
+	ts.handler.ServeHTTP(w, r)
+	return w
+}
+
+// multipartForm body with the fields and one file per files entry
+func multipartForm(t *testing.T, fields map[string]string, files map[string]string) (io.Reader, string) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for name, value := range fields {
+		if err := mw.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, content := range files {
+		fw, err := mw.CreateFormFile(name, name+".csv")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body, mw.FormDataContentType()
+}
+
+// formRequest url encoded form post with the csrf token as cookie and field
+func formRequest(path string, fields url.Values) *http.Request {
+	fields.Set(CSRF_FIELD, testCSRFToken)
+	r := httptest.NewRequest("POST", path, strings.NewReader(fields.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: testCSRFToken})
+	return r
+}
+
+func writeFile(t *testing.T, filename string, content string) {
+	t.Helper()
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
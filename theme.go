@@ -0,0 +1,144 @@
+package sgphelper
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+// defaultTemplates embedded templates, parsed at start so broken
+// templates fail early
+var defaultTemplates = mustTemplates(loadTemplates(nil))
+
+// templates parsed page templates by file name, e.g. index.html
+type templates map[string]*template.Template
+
+// loadTemplates parse the embedded templates, files with the same name
+// in the theme replace them. Files starting with _ are partials with
+// shared blocks, e.g. the header, and are parsed into every page
+func loadTemplates(theme fs.FS) (templates, error) {
+	entries, err := fs.ReadDir(templatesFS, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	read := func(name string) (string, error) {
+		if theme != nil {
+			content, err := fs.ReadFile(theme, path.Join("templates", name))
+			if err == nil {
+				return string(content), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+		}
+		content, err := fs.ReadFile(templatesFS, path.Join("templates", name))
+		return string(content), err
+	}
+
+	partials := []string{}
+	pages := []string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "_") {
+			partials = append(partials, entry.Name())
+		} else {
+			pages = append(pages, entry.Name())
+		}
+	}
+
+	t := templates{}
+	for _, name := range pages {
+		tmpl := template.New(name).Funcs(funcMap)
+		for _, file := range partials {
+			content, err := read(file)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := tmpl.New(file).Parse(content); err != nil {
+				return nil, fmt.Errorf("template %v - %v", file, err)
+			}
+		}
+		content, err := read(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tmpl.Parse(content); err != nil {
+			return nil, fmt.Errorf("template %v - %v", name, err)
+		}
+		t[name] = tmpl
+	}
+	return t, nil
+}
+
+func mustTemplates(t templates, err error) templates {
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// overlayFS serve files of the theme, missing files from the base
+type overlayFS struct {
+	theme fs.FS
+	base  fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	f, err := o.theme.Open(name)
+	if err == nil {
+		return f, nil
+	}
+	return o.base.Open(name)
+}
+
+// SetTheme override templates and static assets with the files in the
+// theme directory, templates/<name>.html and public/<file>
+func (s *Server) SetTheme(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("theme %v is not a directory", dir)
+	}
+
+	theme := os.DirFS(dir)
+	t, err := loadTemplates(theme)
+	if err != nil {
+		return fmt.Errorf("theme %v - %v", dir, err)
+	}
+
+	public, err := fs.Sub(theme, "public")
+	if err != nil {
+		return err
+	}
+	s.templates = t
+	s.static = overlayFS{theme: public, base: s.static}
+	s.logger.Info("theme loaded", "dir", dir)
+	return nil
+}
+
+// render execute the page template, errors are logged and sent as 500
+func (s *Server) render(w http.ResponseWriter, r *http.Request, name string, data any) {
+	if err := s.templates[name].Execute(w, data); err != nil {
+		requestLogger(r).Error("can not render template", "template", name, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,144 @@
+package sgphelper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// SECRET marker in a file next to the data directory, no reply may contain it
+const SECRET = "SECRET-OUTSIDE-DATA-DIR"
+
+type traversalRoute struct {
+	method  string
+	pattern string
+	// body json body, multipart form with csrf token if form is set
+	body string
+	form bool
+}
+
+var traversalRoutes = []traversalRoute{
+	{method: "GET", pattern: "/show/{season}/{race}"},
+	{method: "POST", pattern: "/delete/{season}/{race}", form: true},
+	{method: "POST", pattern: "/addPenalty/{season}/{race}", form: true},
+	{method: "POST", pattern: "/fetch/{season}/{race}", form: true},
+	{method: "POST", pattern: "/upload/{season}", form: true},
+	{method: "POST", pattern: "/laps/{season}/{race}", form: true},
+	{method: "GET", pattern: "/analysis/{season}/{race}"},
+	{method: "GET", pattern: "/export/csv/{season}/{race}/{split}"},
+	{method: "GET", pattern: "/export/qualy/{season}/{race}/{split}"},
+	{method: "POST", pattern: "/uploadEntryList/{season}", form: true},
+	{method: "GET", pattern: "/season/{season}/entrylist"},
+	{method: "GET", pattern: "/branding/{season}"},
+	{method: "POST", pattern: "/branding/{season}", form: true},
+	{method: "GET", pattern: "/logo/{season}"},
+	{method: "POST", pattern: "/api/hooks/results/{season}/{race}", body: `{"qualy":"pos,participant,class,totalTime,laps\n1,Team A,GT3,3600000,30\n","race":"pos,participant,class,totalTime,laps\n1,Team A,GT3,3600000,30\n"}`},
+	{method: "GET", pattern: API_PREFIX + "/seasons/{season}"},
+	{method: "PATCH", pattern: API_PREFIX + "/seasons/{season}", body: `{"name":"Season 2"}`},
+	{method: "DELETE", pattern: API_PREFIX + "/seasons/{season}"},
+	{method: "GET", pattern: API_PREFIX + "/seasons/{season}/standings"},
+	{method: "GET", pattern: API_PREFIX + "/seasons/{season}/awards"},
+	{method: "GET", pattern: API_PREFIX + "/seasons/{season}/entrylist"},
+	{method: "PUT", pattern: API_PREFIX + "/seasons/{season}/entrylist", body: `[{"driver":"Anna Fast","team":"Team A"}]`},
+	{method: "POST", pattern: API_PREFIX + "/seasons/{season}/entrylist", body: `{"driver":"Carl Late","team":"Team C"}`},
+	{method: "GET", pattern: API_PREFIX + "/seasons/{season}/entrylist/Team%20A"},
+	{method: "PUT", pattern: API_PREFIX + "/seasons/{season}/entrylist/Team%20A", body: `{"driver":"Anna Fast","team":"Team A"}`},
+	{method: "DELETE", pattern: API_PREFIX + "/seasons/{season}/entrylist/Team%20A"},
+	{method: "GET", pattern: API_PREFIX + "/seasons/{season}/races"},
+	{method: "POST", pattern: API_PREFIX + "/seasons/{season}/races", body: `{"name":"Race 2"}`},
+	{method: "GET", pattern: API_PREFIX + "/seasons/{season}/races/{race}"},
+	{method: "PATCH", pattern: API_PREFIX + "/seasons/{season}/races/{race}", body: `{"name":"Race 2"}`},
+	{method: "DELETE", pattern: API_PREFIX + "/seasons/{season}/races/{race}"},
+	{method: "GET", pattern: API_PREFIX + "/seasons/{season}/races/{race}/results"},
+	{method: "POST", pattern: API_PREFIX + "/seasons/{season}/races/{race}/penalties", body: `{"pos":1,"penalty":5}`},
+	{method: "GET", pattern: API_PREFIX + "/seasons/{season}/races/{race}/laps"},
+	{method: "PUT", pattern: API_PREFIX + "/seasons/{season}/races/{race}/laps", body: `{"laps":"participant,lap,lapTime\nTeam A,1,100000\n"}`},
+}
+
+// traversalValues path segments pointing outside of the data directory,
+// raw, percent-encoded and absolute
+func traversalValues(secretFile string) map[string]string {
+	return map[string]string{
+		"dot-dot":         "../../../" + filepath.Base(secretFile),
+		"encoded-slash":   "..%2F..%2F..%2F" + filepath.Base(secretFile),
+		"encoded-dot-dot": "%2e%2e%2f%2e%2e%2f%2e%2e%2f" + filepath.Base(secretFile),
+		"encoded-only":    "%2e%2e",
+		"absolute":        url.PathEscape(secretFile),
+	}
+}
+
+func TestPathTraversal(t *testing.T) {
+	ts := newTestServer(t)
+	ts.SetHookToken("hook-token")
+	secretFile := filepath.Join(ts.dir, "secret.csv")
+	writeFile(t, secretFile, "driver,team\n"+SECRET+","+SECRET+"\n")
+	writeFile(t, filepath.Join(ts.dir, "enty_list.csv"), "driver,team\n"+SECRET+","+SECRET+"\n")
+
+	season := ts.data.Seasons["Season 1"]
+	valid := map[string]string{"season": season.Slug, "race": season.Races[0].Slug, "split": "GT3"}
+
+	for _, route := range traversalRoutes {
+		for _, param := range []string{"season", "race", "split"} {
+			if !strings.Contains(route.pattern, "{"+param+"}") {
+				continue
+			}
+			for name, value := range traversalValues(secretFile) {
+				path := route.pattern
+				for p, v := range valid {
+					if p == param {
+						v = value
+					}
+					path = strings.ReplaceAll(path, "{"+p+"}", v)
+				}
+
+				t.Run(route.method+" "+route.pattern+" "+param+" "+name, func(t *testing.T) {
+					w := ts.serve(traversalRequest(t, route, path))
+					if w.Code < 400 || w.Code >= 500 {
+						t.Errorf("%v %v: status %v, want 4xx: %v", route.method, path, w.Code, w.Body.String())
+					}
+					if strings.Contains(w.Body.String(), SECRET) {
+						t.Errorf("%v %v: reply contains a file outside of the data directory", route.method, path)
+					}
+				})
+			}
+		}
+	}
+
+	// the valid season and race are still there
+	if _, found := ts.data.Seasons["Season 1"]; !found || len(ts.data.Seasons["Season 1"].Races) != 1 {
+		t.Errorf("season or race was modified: %+v", ts.data.Seasons)
+	}
+}
+
+func traversalRequest(t *testing.T, route traversalRoute, path string) *http.Request {
+	t.Helper()
+	if route.form {
+		body, contentType := multipartForm(t, map[string]string{
+			CSRF_FIELD:      testCSRFToken,
+			"new_race_name": "Race 2",
+			"pos":           "1",
+			"penalty":       "5",
+			"event_id":      "1",
+			"name":          "League",
+		}, map[string]string{
+			"qualy_result": testResult,
+			"race_result":  testResult,
+			"entry_list":   testEntryList,
+			"lap_data":     testLaps,
+		})
+		r := httptest.NewRequest(route.method, path, body)
+		r.Header.Set("Content-Type", contentType)
+		r.AddCookie(&http.Cookie{Name: CSRF_COOKIE, Value: testCSRFToken})
+		return r
+	}
+
+	r := httptest.NewRequest(route.method, path, strings.NewReader(route.body))
+	if route.body != "" {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	r.Header.Set("Authorization", "Bearer hook-token")
+	return r
+}